@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bismuthsalamander/nurikabe/nurigobe"
+)
+
+// batchResult is one row of the batch summary.
+type batchResult struct {
+	File    string  `json:"file"`
+	Width   int     `json:"width"`
+	Height  int     `json:"height"`
+	Solved  bool    `json:"solved"`
+	Reason  string  `json:"reason,omitempty"`
+	Seconds float64 `json:"seconds"`
+	Guesses int     `json:"guesses"`
+	Error   string  `json:"error,omitempty"`
+}
+
+func batch(args []string) {
+	flags := flag.NewFlagSet("batch", flag.ExitOnError)
+	workers := flags.Int("workers", runtime.NumCPU(), "number of puzzles to solve at once")
+	timeout := flags.Duration("timeout", time.Minute, "longest time to spend on one puzzle")
+	format := flags.String("format", "table", "summary format: `table`, csv or json")
+	out := flags.String("o", "", "write the summary to `file` instead of standard output")
+	flags.Usage = func() {
+		fmt.Printf("usage: %s batch [-workers N] [-timeout duration] [-format table|csv|json] [-o file] <dir|file>...\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() == 0 || *workers < 1 || (*format != "table" && *format != "csv" && *format != "json") {
+		flags.Usage()
+		return
+	}
+
+	files, err := batchFiles(flags.Args())
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		os.Exit(1)
+	}
+	results := solveBatch(files, *workers, *timeout)
+
+	w := os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Printf("error creating %s: %v\n", *out, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		w = f
+	}
+	switch *format {
+	case "csv":
+		err = writeBatchCSV(w, results)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(results)
+	default:
+		err = writeBatchTable(w, results)
+	}
+	if err != nil {
+		fmt.Printf("error writing summary: %v\n", err)
+	}
+}
+
+// batchFiles expands directories into the regular files beneath them,
+// skipping hidden files and directories.
+func batchFiles(args []string) ([]string, error) {
+	files := make([]string, 0)
+	for _, arg := range args {
+		if nurigobe.IsPuzzLinkURL(arg) {
+			files = append(files, arg)
+			continue
+		}
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		err = filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if path != arg && strings.HasPrefix(d.Name(), ".") {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.Type().IsRegular() {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// solveBatch solves files with a pool of workers, each of which parses and
+// solves its own boards, and returns the results in the order of files.
+func solveBatch(files []string, workers int, timeout time.Duration) []batchResult {
+	results := make([]batchResult, len(files))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				results[idx] = solveBatchFile(files[idx], timeout)
+			}
+		}()
+	}
+	for idx := range files {
+		jobs <- idx
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+func solveBatchFile(fn string, timeout time.Duration) batchResult {
+	res := batchResult{File: fn}
+	b, err := nurigobe.GetBoard(fn)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.Width, res.Height = b.Problem.Width, b.Problem.Height
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	opts := nurigobe.DefaultOptions()
+	opts.Trace = true
+	//puzzles are already solved side by side, one per worker
+	opts.GuessWorkers = 1
+	sr, err := nurigobe.SolveBoard(ctx, b, opts)
+	if err != nil {
+		res.Error = err.Error()
+	}
+	res.Solved = sr.Solved
+	if sr.Reason != nil {
+		res.Reason = sr.Reason.Error()
+	}
+	res.Seconds = sr.Elapsed.Seconds()
+	for _, d := range sr.Trace.Steps {
+		if d.Rule == nurigobe.RuleShallowGuess || d.Rule == nurigobe.RuleDeepGuess {
+			res.Guesses++
+		}
+	}
+	return res
+}
+
+func writeBatchTable(w io.Writer, results []batchResult) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "FILE\tSIZE\tSOLVED\tSECONDS\tGUESSES\tREASON\n")
+	solved := 0
+	total := 0.0
+	slowest := -1
+	for idx, r := range results {
+		status := "no"
+		if r.Solved {
+			status = "yes"
+			solved++
+		}
+		reason := r.Reason
+		if r.Error != "" {
+			status = "error"
+			reason = r.Error
+		}
+		total += r.Seconds
+		if slowest < 0 || r.Seconds > results[slowest].Seconds {
+			slowest = idx
+		}
+		fmt.Fprintf(tw, "%s\t%dx%d\t%s\t%.4f\t%d\t%s\n", r.File, r.Width, r.Height, status, r.Seconds, r.Guesses, reason)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	summary := fmt.Sprintf("\n%d/%d solved, %.4f seconds of solving", solved, len(results), total)
+	if slowest >= 0 {
+		summary += fmt.Sprintf(" (slowest: %s, %.4f)", results[slowest].File, results[slowest].Seconds)
+	}
+	_, err := fmt.Fprintf(w, "%s\n", summary)
+	return err
+}
+
+func writeBatchCSV(w io.Writer, results []batchResult) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"file", "width", "height", "solved", "reason", "seconds", "guesses", "error"})
+	for _, r := range results {
+		cw.Write([]string{
+			r.File,
+			strconv.Itoa(r.Width),
+			strconv.Itoa(r.Height),
+			strconv.FormatBool(r.Solved),
+			r.Reason,
+			strconv.FormatFloat(r.Seconds, 'f', 4, 64),
+			strconv.Itoa(r.Guesses),
+			r.Error,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/bismuthsalamander/nurikabe/nurigobe"
+)
+
+func checkUnique(args []string) {
+	flags := flag.NewFlagSet("check-unique", flag.ExitOnError)
+	limit := flags.Int("limit", 2, "stop once more than this many solutions are found")
+	show := flags.Bool("show", true, "print the solutions found")
+	flags.Usage = func() {
+		fmt.Printf("usage: %s check-unique [-limit N] [-show=false] [problem.txt|url]\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return
+	}
+
+	fn := flags.Arg(0)
+	b, err := nurigobe.GetBoard(fn)
+	if err != nil {
+		fmt.Printf("error reading problem %s: %v\n", fn, err)
+		return
+	}
+	sc, err := nurigobe.CountSolutionsContext(context.Background(), b, *limit)
+	if err != nil {
+		fmt.Printf("error counting solutions of %s: %v\n", fn, err)
+	}
+	fmt.Printf("%s: %v\n", fn, sc)
+	if *show {
+		for idx, soln := range sc.Solutions {
+			fmt.Printf("\nSolution %d:\n%v", idx+1, soln)
+		}
+	}
+	if !sc.IsUnique() {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/bismuthsalamander/nurikabe/nurigobe"
+)
+
+func generate(args []string) {
+	flags := flag.NewFlagSet("generate", flag.ExitOnError)
+	width := flags.Int("width", 10, "puzzle width")
+	height := flags.Int("height", 10, "puzzle height")
+	seed := flags.Int64("seed", time.Now().UnixNano(), "random seed")
+	maxIsland := flags.Int("max-island", 9, "largest island size")
+	url := flags.Bool("url", false, "print a puzz.link URL instead of a problem file")
+	flags.Usage = func() {
+		fmt.Printf("usage: %s generate [-width W] [-height H] [-seed N] [-max-island N] [-url]\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return
+	}
+
+	opts := nurigobe.DefaultGeneratorOptions(*width, *height, *seed)
+	opts.MaxIslandSize = *maxIsland
+	def, err := nurigobe.GenerateContext(context.Background(), opts)
+	if err != nil {
+		fmt.Printf("error generating puzzle: %v\n", err)
+		os.Exit(1)
+	}
+	if *url {
+		fmt.Printf("%s\n", def.PuzzLinkURL())
+		return
+	}
+	fmt.Printf("%v\n", def)
+}
@@ -2,8 +2,9 @@ module github.com/bismuthsalamander/nurikabe
 
 go 1.19
 
+require github.com/chzyer/readline v1.5.1
+
 require (
-	github.com/chzyer/readline v1.5.1 // indirect
 	github.com/felixge/fgprof v0.9.3 // indirect
 	github.com/google/pprof v0.0.0-20230207041349-798e818bf904 // indirect
 	github.com/ianlancetaylor/demangle v0.0.0-20220517205856-0058ec4f073c // indirect
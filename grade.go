@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/bismuthsalamander/nurikabe/nurigobe"
+)
+
+func grade(args []string) {
+	flags := flag.NewFlagSet("grade", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Printf("usage: %s grade [problem.txt|url]...\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return
+	}
+
+	for _, fn := range flags.Args() {
+		b, err := nurigobe.GetBoard(fn)
+		if err != nil {
+			fmt.Printf("error reading problem %s: %v\n", fn, err)
+			continue
+		}
+		g, err := nurigobe.GradeBoard(context.Background(), b)
+		if err != nil {
+			fmt.Printf("error grading %s: %v\n", fn, err)
+		}
+		fmt.Printf("%s: %v", fn, g)
+	}
+}
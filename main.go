@@ -1,41 +1,132 @@
 package main
 
 import (
-    "os"
+	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"os"
+	"runtime"
 	"sync"
 	"time"
-    "github.com/bismuthsalamander/nurikabe/nurigobe"
+
+	"github.com/bismuthsalamander/nurikabe/nurigobe"
 )
 
 func main() {
-    if len(os.Args) != 2 {
-        fmt.Printf("usage: %s [problem.txt]\n", os.Args[0])
-        return
-    }
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "check-unique":
+			checkUnique(os.Args[2:])
+			return
+		case "generate":
+			generate(os.Args[2:])
+			return
+		case "grade":
+			grade(os.Args[2:])
+			return
+		case "render":
+			render(os.Args[2:])
+			return
+		case "play":
+			play(os.Args[2:])
+			return
+		case "repl":
+			repl(os.Args[2:])
+			return
+		case "serve":
+			serve(os.Args[2:])
+			return
+		case "batch":
+			batch(os.Args[2:])
+			return
+		}
+	}
+	solve(os.Args[1:])
+}
+
+func solve(args []string) {
+	flags := flag.NewFlagSet("solve", flag.ExitOnError)
+	trace := flags.String("trace", "", "print every deduction as `text` or json")
+	asJSON := flags.Bool("json", false, "print the result as JSON")
+	guessWorkers := flags.Int("guess-workers", runtime.NumCPU(), "number of guesses to test at once")
+	deterministic := flags.Bool("deterministic", false, "make parallel guesses pick the same cells as sequential ones")
+	flags.Usage = func() {
+		fmt.Printf("usage: %s [-trace text|json] [-json] [-guess-workers N] [-deterministic] [problem.txt|url]\n", os.Args[0])
+		fmt.Printf("       %s check-unique [-limit N] [problem.txt|url]\n", os.Args[0])
+		fmt.Printf("       %s generate [-width W] [-height H] [-seed N]\n", os.Args[0])
+		fmt.Printf("       %s grade [problem.txt|url]...\n", os.Args[0])
+		fmt.Printf("       %s play [problem.txt|url]\n", os.Args[0])
+		fmt.Printf("       %s repl [problem.txt|url]\n", os.Args[0])
+		fmt.Printf("       %s serve [-addr host:port] [-timeout duration]\n", os.Args[0])
+		fmt.Printf("       %s batch [-workers N] [-timeout duration] [-format table|csv|json] <dir|file>...\n", os.Args[0])
+		fmt.Printf("       %s render --svg|--png|--gif [-o file] [-solve] [problem.txt|url]\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 || (*trace != "" && *trace != "text" && *trace != "json") {
+		flags.Usage()
+		return
+	}
 
-    fn := os.Args[1]
-	b, err := nurigobe.GetBoardFromFile(fn)
-    if err != nil {
-        fmt.Printf("error reading problem file %s: %v\n", fn, err)
-        return
-    }
+	fn := flags.Arg(0)
+	b, err := nurigobe.GetBoard(fn)
+	if err != nil {
+		fmt.Printf("error reading problem %s: %v\n", fn, err)
+		return
+	}
 
 	startNano := time.Now().UnixNano()
-	s := nurigobe.NewSolver(b)
+	opts := nurigobe.DefaultOptions()
+	opts.Trace = *trace != ""
+	opts.GuessWorkers = *guessWorkers
+	opts.DeterministicGuesses = *deterministic
 	var wg sync.WaitGroup
-	wg.Add(1)
-	go s.PrintUpdates(&wg)
-	s.InitSolve()
-	s.AutoSolve(true, false)
-	close(s.Progress)
+	if !*asJSON {
+		opts.Progress = make(chan nurigobe.ProgressUpdate, b.Problem.Size*2)
+		wg.Add(1)
+		go nurigobe.PrintProgress(opts.Progress, &wg)
+	}
+	res, err := nurigobe.SolveBoard(context.Background(), b, opts)
 	wg.Wait()
-	fmt.Printf("%v\n", b.String())
+	if err != nil {
+		fmt.Printf("error solving %s: %v\n", fn, err)
+	}
+	if *asJSON {
+		out, err := json.MarshalIndent(res, "", "  ")
+		if err != nil {
+			fmt.Printf("error encoding result: %v\n", err)
+			return
+		}
+		fmt.Printf("%s\n", out)
+		return
+	}
+	fmt.Printf("%v\n", res.Board.String())
 	stopNano := time.Now().UnixNano()
-	if sol, reason := b.IsSolved(); sol == false {
-		fmt.Printf("Not solved (%v)\n", reason)
+	if !res.Solved {
+		fmt.Printf("Not solved (%v)\n", res.Reason)
 	}
 	fmt.Printf("Total duration: %.4f\n", float64(stopNano-startNano)/1000000000.0)
+	if !nurigobe.IsPuzzLinkURL(fn) {
+		if j, err := nurigobe.GetJankoFromFile(fn); err == nil && j.Solution != nil {
+			if err := j.Check(res.Board); err != nil {
+				fmt.Printf("Does not match published solution (%v)\n", err)
+			} else {
+				fmt.Printf("Matches published solution\n")
+			}
+		}
+	}
+	switch *trace {
+	case "text":
+		fmt.Printf("\n%v", res.Trace)
+	case "json":
+		out, err := json.MarshalIndent(res.Trace, "", "  ")
+		if err != nil {
+			fmt.Printf("error encoding trace: %v\n", err)
+			return
+		}
+		fmt.Printf("%s\n", out)
+	}
 }
 
 // TODO: have group versions of RemoveFromPossibility and MarkPainted - only one trip through the possibility sets
@@ -0,0 +1,125 @@
+package nurigobe
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"io"
+	"strings"
+)
+
+// RecordedMark is one cell marked by a solver, along with the action it was
+// performing at the time.
+type RecordedMark struct {
+	Coord  Coordinate
+	Cell   Cell
+	Action string
+}
+
+// Recording is the sequence of cells a solver marked, starting from the
+// state of the board when the recording began.
+type Recording struct {
+	Problem ProblemDef
+	Start   [][]Cell
+	Marks   []RecordedMark
+}
+
+func NewRecording(b *Board) *Recording {
+	start := NewGrid(b.Problem.Width, b.Problem.Height)
+	for r := range b.Grid {
+		copy(start[r], b.Grid[r])
+	}
+	return &Recording{b.Problem, start, make([]RecordedMark, 0, b.Problem.Size)}
+}
+
+func (rec *Recording) Add(c Coordinate, cell Cell, action string) {
+	rec.Marks = append(rec.Marks, RecordedMark{c, cell, action})
+}
+
+// GIFOptions controls the output of Recording.GIF.
+type GIFOptions struct {
+	ImageOptions
+	// MarksPerFrame is the number of marks shown by each frame; raise it to
+	// keep the animation of a large board short.
+	MarksPerFrame int
+	// Delay is the time each frame is shown, in hundredths of a second.
+	Delay int
+	// FinalDelay is the time the last frame is shown.
+	FinalDelay int
+	// Captions adds the solver's action for each frame below the board.
+	Captions bool
+}
+
+func DefaultGIFOptions() GIFOptions {
+	img := DefaultImageOptions()
+	img.CellSize = 24
+	return GIFOptions{img, 1, 20, 300, true}
+}
+
+// GIF animates the recording: the first frame shows the starting board and
+// each following frame adds MarksPerFrame marks, highlighting them.
+func (rec *Recording) GIF(opts GIFOptions) *gif.GIF {
+	per := opts.MarksPerFrame
+	if per < 1 {
+		per = 1
+	}
+	b := &Board{Problem: rec.Problem, Grid: NewGrid(rec.Problem.Width, rec.Problem.Height)}
+	for r := range rec.Start {
+		copy(b.Grid[r], rec.Start[r])
+	}
+	palette := color.Palette{opts.UnknownColor, opts.WallColor, opts.ClearColor, opts.GridColor, opts.ClueColor, opts.HighlightColor}
+	anim := &gif.GIF{}
+	addFrame := func(highlight *CoordinateSet, caption string) {
+		frameOpts := opts.ImageOptions
+		frameOpts.Highlight = highlight
+		anim.Image = append(anim.Image, gifFrame(b, frameOpts, palette, caption, opts.Captions))
+		anim.Delay = append(anim.Delay, opts.Delay)
+	}
+	addFrame(nil, "Start")
+	for i := 0; i < len(rec.Marks); i += per {
+		marked := EmptyCoordinateSet()
+		end := i + per
+		if end > len(rec.Marks) {
+			end = len(rec.Marks)
+		}
+		for _, m := range rec.Marks[i:end] {
+			b.Grid[m.Coord.Row][m.Coord.Col] = m.Cell
+			marked.Add(m.Coord)
+		}
+		addFrame(marked, rec.Marks[end-1].Action)
+	}
+	anim.Delay[len(anim.Delay)-1] = opts.FinalDelay
+	return anim
+}
+
+// WriteGIF encodes the recording's GIF.
+func (rec *Recording) WriteGIF(w io.Writer, opts GIFOptions) error {
+	return gif.EncodeAll(w, rec.GIF(opts))
+}
+
+// gifFrame draws b and, if captions is set, a band below it with the caption
+// in the board's clue colour, truncated to fit.
+func gifFrame(b *Board, opts ImageOptions, palette color.Palette, caption string, captions bool) *image.Paletted {
+	board := b.Image(opts)
+	bounds := board.Bounds()
+	scale := 2
+	if bounds.Dx() < 200 {
+		scale = 1
+	}
+	if captions {
+		bounds.Max.Y += (glyphHeight + 4) * scale
+	}
+	img := image.NewPaletted(bounds, palette)
+	fillRect(img, bounds, opts.UnknownColor)
+	draw.Draw(img, board.Bounds(), board, image.Point{}, draw.Src)
+	if captions {
+		text := []rune(strings.ToUpper(caption))
+		fits := (bounds.Dx() - 4*scale + scale) / ((glyphWidth + 1) * scale)
+		if len(text) > fits {
+			text = text[:fits]
+		}
+		drawText(img, image.Pt(2*scale, board.Bounds().Max.Y+2*scale), scale, string(text), opts.ClueColor)
+	}
+	return img
+}
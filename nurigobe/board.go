@@ -3,7 +3,6 @@ package nurigobe
 import (
 	"fmt"
 	"math"
-	"os"
 	"sort"
 	"strings"
 )
@@ -39,235 +38,6 @@ func (c Coordinate) ManhattanDistance(target Coordinate) int {
 	return int(math.Abs(float64(target.Row-c.Row)) + math.Abs(float64(target.Col-c.Col)))
 }
 
-// TODO: make this a generic?
-type CoordinateSet struct {
-	Map map[Coordinate]bool
-}
-
-func EmptyCoordinateSet() *CoordinateSet {
-	return &CoordinateSet{make(map[Coordinate]bool)}
-}
-
-func EmptyCoordinateSetSz(sz int) *CoordinateSet {
-	return &CoordinateSet{make(map[Coordinate]bool, sz)}
-}
-
-func SingleCoordinateSet(c Coordinate) *CoordinateSet {
-	cs := EmptyCoordinateSet()
-	cs.Add(c)
-	return cs
-}
-
-func (s *CoordinateSet) Size() int {
-	return len(s.Map)
-}
-
-func (s *CoordinateSet) IsEmpty() bool {
-	return len(s.Map) == 0
-}
-
-func (s *CoordinateSet) Add(c Coordinate) {
-	s.Map[c] = true
-}
-
-func (s *CoordinateSet) Del(c Coordinate) {
-	delete(s.Map, c)
-}
-
-func (s *CoordinateSet) DelAll(other *CoordinateSet) {
-	for v := range other.Map {
-		s.Del(v)
-	}
-}
-
-func (cs *CoordinateSet) AddAll(other *CoordinateSet) {
-	for v := range other.Map {
-		cs.Add(v)
-	}
-}
-
-func (s *CoordinateSet) Plus(other *CoordinateSet) *CoordinateSet {
-	cs := s.Copy()
-	for v := range other.Map {
-		cs.Add(v)
-	}
-	return cs
-}
-
-func (s *CoordinateSet) Minus(other *CoordinateSet) *CoordinateSet {
-	cs := s.Copy()
-	for v := range other.Map {
-		cs.Del(v)
-	}
-	return cs
-}
-
-func (s *CoordinateSet) Contains(c Coordinate) bool {
-	if val, ok := s.Map[c]; val && ok {
-		return true
-	}
-	return false
-}
-
-func (s *CoordinateSet) ContainsAtLeastOne(other *CoordinateSet) bool {
-	for k := range other.Map {
-		if s.Contains(k) {
-			return true
-		}
-	}
-	return false
-}
-
-func (s *CoordinateSet) ToSlice() []Coordinate {
-	out := make([]Coordinate, 0, len(s.Map))
-	for k := range s.Map {
-		out = append(out, k)
-	}
-	return out
-}
-
-func (s *CoordinateSet) Copy() *CoordinateSet {
-	cs := CoordinateSet{make(map[Coordinate]bool, s.Size())}
-	for k := range s.Map {
-		cs.Add(k)
-	}
-	return &cs
-}
-
-func (s *CoordinateSet) OneMember() Coordinate {
-	for k := range s.Map {
-		return k
-	}
-	return NilCoordinate()
-}
-
-func (s *CoordinateSet) BordersCoordinate(c Coordinate) bool {
-	if s.Contains(c) {
-		return false
-	}
-	for k := range s.Map {
-		if AreAdjacent(k, c) {
-			return true
-		}
-	}
-	return false
-}
-
-func (cs *CoordinateSet) Equals(other *CoordinateSet) bool {
-	if cs.Size() != other.Size() {
-		return false
-	}
-	for k := range cs.Map {
-		if !other.Contains(k) {
-			return false
-		}
-	}
-	return true
-}
-
-func (superset *CoordinateSet) ContainsAll(subset *CoordinateSet) bool {
-	for tester := range subset.Map {
-		if !superset.Contains(tester) {
-			return false
-		}
-	}
-	return true
-}
-
-func (s *CoordinateSet) CanAddWall(c Coordinate) bool {
-	/**
-	 * Diagram for the algorithm below:
-	 *
-	 * n is a slice containing flags for whether neighbors are painted.
-	 * The asterisk represents c, and the digits represent indexes into n
-	 * indicating whether that cell is painted.
-	 *
-	 *  012
-	 *  3*4
-	 *  567
-	 *
-	**/
-
-	n := make([]bool, 8)
-	idx := 0
-	for dr := -1; dr < 2; dr++ {
-		for dc := -1; dc < 2; dc++ {
-			if dr == 0 && dc == 0 {
-				continue
-			}
-			n[idx] = s.Contains(c.Translate(dr, dc))
-			idx++
-		}
-	}
-	//Maybe the compiler is smart enough to optimize these conditionals for us;
-	//just in case, I'll do it manually
-	if n[1] {
-		if n[0] && n[3] {
-			return false
-		}
-		if n[2] && n[4] {
-			return false
-		}
-	}
-	if n[6] {
-		if n[3] && n[5] {
-			return false
-		}
-		if n[4] && n[7] {
-			return false
-		}
-	}
-	return true
-}
-
-func (s *CoordinateSet) String() string {
-	out := ""
-	for m := range s.Map {
-		out += fmt.Sprintf("(r%d, c%d) ", m.Row, m.Col)
-	}
-	return out
-}
-
-func (s *CoordinateSet) SerializedString() string {
-	slice := s.ToSlice()
-	sort.Sort(CoordinateSlice(slice))
-	out := fmt.Sprintf("%v", slice)
-	return out
-}
-
-type CoordinateSlice []Coordinate
-
-func (c CoordinateSlice) Len() int      { return len(c) }
-func (c CoordinateSlice) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
-func (c CoordinateSlice) Less(i, j int) bool {
-	return c[i].Row < c[j].Row || (c[i].Row == c[j].Row && c[i].Col < c[j].Col)
-}
-
-type CoordinateSetSet struct {
-	Map map[string]bool
-}
-
-func EmptyCoordinateSetSet() *CoordinateSetSet {
-	css := CoordinateSetSet{}
-	css.Map = make(map[string]bool)
-	return &css
-}
-
-func (css *CoordinateSetSet) Add(cs *CoordinateSet) bool {
-	str := cs.SerializedString()
-	if _, ok := css.Map[str]; ok {
-		return false
-	}
-	css.Map[str] = true
-	return true
-}
-
-func (css *CoordinateSetSet) Contains(cs *CoordinateSet) bool {
-	str := cs.SerializedString()
-	_, ok := css.Map[str]
-	return ok
-}
-
 type IslandSpec struct {
 	Col  int
 	Row  int
@@ -283,6 +53,9 @@ type Island struct {
 	Root            Coordinate
 	Possibilities   []*CoordinateSet
 	Reachable       *CoordinateSet
+	// Liberties holds the unknown cells next to the island, kept up to
+	// date by the board the island belongs to.
+	Liberties *CoordinateSet
 }
 
 func (i *Island) Clone() *Island {
@@ -295,12 +68,16 @@ func (i *Island) Clone() *Island {
 		i.Root,
 		nil,
 		nil,
+		nil,
+	}
+	if i.Liberties != nil {
+		new.Liberties = i.Liberties.Copy()
 	}
 	if new.IslandType == CLEAR_ISLAND {
 		//we can just copy the pointers because a possibility is never modified once it's in place.
 		new.Possibilities = make([]*CoordinateSet, len(i.Possibilities))
 		copy(new.Possibilities, i.Possibilities)
-		i.Reachable = i.Reachable.Copy()
+		new.Reachable = i.Reachable.Copy()
 	}
 	return &new
 }
@@ -339,15 +116,15 @@ const CLEAR_ISLAND = 0
 const WALL_ISLAND = 1
 
 func MakeRootedIsland(r int, c int, sz int) *Island {
-	return &Island{SingleCoordinateSet(Coordinate{r, c}), 1, sz, sz == 1, CLEAR_ISLAND, Coordinate{r, c}, make([]*CoordinateSet, 0, 10), EmptyCoordinateSet()}
+	return &Island{SingleCoordinateSet(Coordinate{r, c}), 1, sz, sz == 1, CLEAR_ISLAND, Coordinate{r, c}, make([]*CoordinateSet, 0, 10), EmptyCoordinateSet(), nil}
 }
 
 func MakeUnrootedIsland(r int, c int) *Island {
-	return &Island{SingleCoordinateSet(Coordinate{r, c}), 1, 0, false, CLEAR_ISLAND, NilCoordinate(), make([]*CoordinateSet, 0, 10), EmptyCoordinateSet()}
+	return &Island{SingleCoordinateSet(Coordinate{r, c}), 1, 0, false, CLEAR_ISLAND, NilCoordinate(), make([]*CoordinateSet, 0, 10), EmptyCoordinateSet(), nil}
 }
 
 func MakeWallIsland(r int, c int) *Island {
-	return &Island{SingleCoordinateSet(Coordinate{r, c}), 1, 0, false, WALL_ISLAND, NilCoordinate(), nil, nil}
+	return &Island{SingleCoordinateSet(Coordinate{r, c}), 1, 0, false, WALL_ISLAND, NilCoordinate(), nil, nil, nil}
 }
 
 type ProblemDef struct {
@@ -358,7 +135,21 @@ type ProblemDef struct {
 	TargetWallCount int
 }
 
+// NeedsTokens reports whether the problem has a clue too big for the
+// single-character grid format.
+func (p ProblemDef) NeedsTokens() bool {
+	for _, spec := range p.IslandSpecs {
+		if spec.Size > MaxCharClue {
+			return true
+		}
+	}
+	return false
+}
+
 func (p ProblemDef) String() string {
+	if p.NeedsTokens() {
+		return strings.TrimSuffix(BoardFromDef(p).TokenString(), "\n")
+	}
 	s := make([]string, 0)
 	for ri := 0; ri < p.Height; ri++ {
 		s = append(s, strings.Repeat("_", p.Width))
@@ -377,6 +168,28 @@ type Board struct {
 	WallIslands  []*Island
 	DiagonalSets []*CoordinateSet
 	TotalMarked  int
+	// Watch times the work done on the board. Clones get their own.
+	Watch *Stopwatch
+	// islandCells, wallCells and diagonalCells track which island, wall
+	// island and diagonal set each marked cell belongs to.
+	islandCells   *cellSets[*Island]
+	wallCells     *cellSets[*Island]
+	diagonalCells *cellSets[*CoordinateSet]
+	// reachers lists, for each cell, the islands that have been given a
+	// possibility containing it, so that clearing a cell only strips the
+	// islands that could reach it. Entries go stale as islands lose
+	// possibilities or merge into others.
+	reachers [][]*Island
+	// islandQueue and wallQueue list the islands and wall islands whose
+	// liberties have changed since the one-liberty rules last looked.
+	islandQueue []*Island
+	wallQueue   []*Island
+	// markQueue and possibilityQueue list the cells marked and the islands
+	// whose possibilities have changed since the solver last propagated.
+	markQueue        []Coordinate
+	possibilityQueue []*Island
+	// trail undoes changes back to a checkpoint.
+	trail trail
 }
 
 func NewGrid(w int, h int) [][]Cell {
@@ -388,16 +201,21 @@ func NewGrid(w int, h int) [][]Cell {
 }
 
 func BoardFromDef(def ProblemDef) *Board {
-	b := Board{def, NewGrid(def.Width, def.Height), NewGrid(def.Width, def.Height), make([]*Island, 0), make([]*Island, 0), make([]*CoordinateSet, 0), 0}
+	b := Board{def, NewGrid(def.Width, def.Height), NewGrid(def.Width, def.Height), make([]*Island, 0), make([]*Island, 0), make([]*CoordinateSet, 0), 0, NewStopwatch(), newCellSets[*Island](def.Size), newCellSets[*Island](def.Size), newCellSets[*CoordinateSet](def.Size), make([][]*Island, def.Size), nil, nil, nil, nil, trail{}}
 	for _, spec := range b.Problem.IslandSpecs {
+		c := Coordinate{spec.Row, spec.Col}
 		b.Grid[spec.Row][spec.Col] = CLEAR
 		b.TotalMarked++
-		b.Islands = append(b.Islands, MakeRootedIsland(spec.Row, spec.Col, spec.Size))
-		b.DiagonalSets = append(b.DiagonalSets, SingleCoordinateSet(Coordinate{spec.Row, spec.Col}))
+		b.addIsland(c, MakeRootedIsland(spec.Row, spec.Col, spec.Size))
+		b.addDiagonalSet(c)
 	}
 	return &b
 }
 
+func (b *Board) cellIndex(c Coordinate) int {
+	return c.Row*b.Problem.Width + c.Col
+}
+
 func (b *Board) ClearScratchGrid() {
 	for r := 0; r < b.Problem.Height; r++ {
 		for c := 0; c < b.Problem.Width; c++ {
@@ -407,39 +225,54 @@ func (b *Board) ClearScratchGrid() {
 }
 
 func (b *Board) Clone() *Board {
-	Watch.Start("Clone board")
-	defer Watch.Stop("Clone board")
+	b.Watch.Start("Clone board")
+	defer b.Watch.Stop("Clone board")
 	//merge the wall islands
 	//new := BoardFromDef(b.Problem)
-	new := Board{b.Problem, NewGrid(b.Problem.Width, b.Problem.Height), b.ScratchGrid, make([]*Island, 0, len(b.Islands)), make([]*Island, 0, len(b.WallIslands)), make([]*CoordinateSet, 0, len(b.DiagonalSets)), b.TotalMarked}
+	new := Board{b.Problem, NewGrid(b.Problem.Width, b.Problem.Height), NewGrid(b.Problem.Width, b.Problem.Height), make([]*Island, 0, len(b.Islands)), make([]*Island, 0, len(b.WallIslands)), make([]*CoordinateSet, 0, len(b.DiagonalSets)), b.TotalMarked, NewStopwatch(), nil, nil, nil, nil, nil, nil, nil, nil, trail{}}
 	for r := 0; r < b.Problem.Height; r++ {
 		for c := 0; c < b.Problem.Width; c++ {
 			new.Grid[r][c] = b.Grid[r][c]
 		}
 	}
+	islands := make(map[*Island]*Island, len(b.Islands)+len(b.WallIslands))
 	for _, i := range b.Islands {
-		new.Islands = append(new.Islands, i.Clone())
+		islands[i] = i.Clone()
+		new.Islands = append(new.Islands, islands[i])
 	}
 	for _, i := range b.WallIslands {
-		new.WallIslands = append(new.WallIslands, i.Clone())
+		islands[i] = i.Clone()
+		new.WallIslands = append(new.WallIslands, islands[i])
 	}
+	diagonals := make(map[*CoordinateSet]*CoordinateSet, len(b.DiagonalSets))
 	for _, cs := range b.DiagonalSets {
-		new.DiagonalSets = append(new.DiagonalSets, cs.Copy())
-	}
-	if new.TotalMarked != b.TotalMarked {
-		fmt.Printf("me %v new %v\n", b.TotalMarked, new.TotalMarked)
-		os.Exit(0)
+		diagonals[cs] = cs.Copy()
+		new.DiagonalSets = append(new.DiagonalSets, diagonals[cs])
+	}
+	new.islandCells = b.islandCells.clone(islands)
+	new.wallCells = b.wallCells.clone(islands)
+	new.diagonalCells = b.diagonalCells.clone(diagonals)
+	new.reachers = make([][]*Island, len(b.reachers))
+	for k, reachers := range b.reachers {
+		for _, o := range reachers {
+			if o = islands[b.currentIsland(o)]; o != nil && !islandIn(new.reachers[k], o) {
+				new.reachers[k] = append(new.reachers[k], o)
+			}
+		}
 	}
+	new.islandQueue = cloneQueue(b.islandQueue, islands)
+	new.wallQueue = cloneQueue(b.wallQueue, islands)
+	new.markQueue = append([]Coordinate(nil), b.markQueue...)
+	new.possibilityQueue = cloneQueue(b.possibilityQueue, islands)
 	return &new
 }
 
+// IslandAt returns the island containing the clear cell at (r, c), or nil.
 func (b *Board) IslandAt(r int, c int) *Island {
-	for _, i := range b.Islands {
-		if i.Members.Contains(Coordinate{r, c}) {
-			return i
-		}
+	if !b.AreInBounds(r, c) {
+		return nil
 	}
-	return nil
+	return b.islandCells.get(b.cellIndex(Coordinate{r, c}))
 }
 
 func AreAdjacent(a Coordinate, b Coordinate) bool {
@@ -455,7 +288,7 @@ func AreDiagonallyAdjacent(a Coordinate, b Coordinate) bool {
 }
 
 func (i *Island) BordersCell(c Coordinate) bool {
-	for m := range i.Members.Map {
+	for _, m := range i.Members.ToSlice() {
 		if AreAdjacent(m, c) {
 			return true
 		}
@@ -463,28 +296,6 @@ func (i *Island) BordersCell(c Coordinate) bool {
 	return false
 }
 
-func (cs *CoordinateSet) BordersSet(other *CoordinateSet) bool {
-	for m1 := range cs.Map {
-		for m2 := range other.Map {
-			if AreAdjacent(m1, m2) {
-				return true
-			}
-		}
-	}
-	return false
-}
-
-func (cs *CoordinateSet) BordersSetDiagonally(other *CoordinateSet) bool {
-	for m1 := range cs.Map {
-		for m2 := range other.Map {
-			if AreDiagonallyAdjacent(m1, m2) {
-				return true
-			}
-		}
-	}
-	return false
-}
-
 func (i *Island) BordersIsland(other *Island) bool {
 	return i.Members.BordersSet(other.Members)
 }
@@ -518,75 +329,53 @@ func (i *Island) Absorb(other *Island) {
 	}
 	cs := i.Members.Plus(other.Members)
 	i.Members = cs
+	if i.Liberties != nil && other.Liberties != nil {
+		i.Liberties = i.Liberties.Plus(other.Liberties)
+	}
 }
 
-func (b *Board) MergeAll() {
-	b.MergeIslands()
-	b.MergeDiagonalSets()
-	b.MergeWallIslands()
-}
-
-func (b *Board) MergeIslands() {
-	Watch.Start("MergeIslands")
-	defer Watch.Stop("MergeIslands")
-	changed := true
-	for changed {
-		changed = false
-		for i := 0; i < len(b.Islands); i++ {
-			for j := i + 1; j < len(b.Islands); j++ {
-				if b.Islands[i].BordersIsland(b.Islands[j]) {
-					changed = true
-					b.Islands[i].Absorb(b.Islands[j])
-					b.Islands[j] = b.Islands[len(b.Islands)-1]
-					b.Islands = b.Islands[:len(b.Islands)-1]
-					j--
-				}
-			}
-		}
-	}
-	b.PopulateUnrootedPossibilities()
-	b.StripAllPossibilities()
-}
-
-func (b *Board) MergeDiagonalSets() {
-	Watch.Start("MergeDiagonalSets")
-	defer Watch.Stop("MergeDiagonalSets")
-	changed := true
-	for changed {
-		changed = false
-		for i := 0; i < len(b.DiagonalSets); i++ {
-			for j := i + 1; j < len(b.DiagonalSets); j++ {
-				if b.DiagonalSets[i].BordersSetDiagonally(b.DiagonalSets[j]) {
-					changed = true
-					b.DiagonalSets[i].AddAll(b.DiagonalSets[j])
-					RemoveFromSlice(&b.DiagonalSets, j)
-					j--
-				}
-			}
-		}
-	}
+// addIsland adds i, which holds only the clear cell c, and merges it with
+// the islands next to c.
+func (b *Board) addIsland(c Coordinate, i *Island) {
+	b.Watch.Start("MergeIslands")
+	defer b.Watch.Stop("MergeIslands")
+	b.takeLiberty(c)
+	i.Liberties = b.NeighborsWith(i.Members, UNKNOWN)
+	b.islandCells.add(&b.trail, &b.Islands, b.cellIndex(c), i)
+	mergeAt(b, b.islandCells, &b.Islands, c, false, b.absorbIsland)
+	b.queueLiberties(b.islandCells.get(b.cellIndex(c)))
 }
 
-func (b *Board) MergeWallIslands() {
-	Watch.Start("MergeWallIslands")
-	defer Watch.Stop("MergeWallIslands")
-	changed := true
-	for changed {
-		changed = false
-		newWallIslands := make([]*Island, 0)
-		for i := 0; i < len(b.WallIslands); i++ {
-			for j := i + 1; j < len(b.WallIslands); j++ {
-				if b.WallIslands[i].BordersIsland(b.WallIslands[j]) {
-					changed = true
-					b.WallIslands[i].Absorb(b.WallIslands[j])
-					b.WallIslands[j] = b.WallIslands[len(b.WallIslands)-1]
-					b.WallIslands = b.WallIslands[:len(b.WallIslands)-1]
-					j--
-				}
-			}
-			newWallIslands = append(newWallIslands, b.WallIslands[i])
-		}
-		b.WallIslands = newWallIslands
+// addWallIsland is addIsland for a painted cell.
+func (b *Board) addWallIsland(c Coordinate, i *Island) {
+	b.Watch.Start("MergeWallIslands")
+	defer b.Watch.Stop("MergeWallIslands")
+	b.takeLiberty(c)
+	i.Liberties = b.NeighborsWith(i.Members, UNKNOWN)
+	b.wallCells.add(&b.trail, &b.WallIslands, b.cellIndex(c), i)
+	mergeAt(b, b.wallCells, &b.WallIslands, c, false, b.absorbIsland)
+	b.queueLiberties(b.wallCells.get(b.cellIndex(c)))
+}
+
+// addDiagonalSet adds a diagonal set holding the clear cell c and merges it
+// with the sets of the clear cells around c.
+func (b *Board) addDiagonalSet(c Coordinate) {
+	b.Watch.Start("MergeDiagonalSets")
+	defer b.Watch.Stop("MergeDiagonalSets")
+	cs := SingleCoordinateSet(c)
+	b.diagonalCells.add(&b.trail, &b.DiagonalSets, b.cellIndex(c), cs)
+	mergeAt(b, b.diagonalCells, &b.DiagonalSets, c, true, func(into *CoordinateSet, from *CoordinateSet) {
+		b.recordSet(into)
+		into.AddAll(from)
+	})
+}
+
+func (b *Board) absorbIsland(into *Island, from *Island) {
+	b.recordIsland(into)
+	into.Absorb(from)
+	if into.IslandType == CLEAR_ISLAND {
+		b.queuePossibilities(from)
+		b.queuePossibilities(into)
 	}
 }
 
@@ -598,33 +387,86 @@ func (b *Board) MarkClear(r int, c int) bool {
 	if b.Grid[r][c] == CLEAR {
 		return false
 	}
+	b.recordCell(r, c)
 	b.Grid[r][c] = CLEAR
 	b.TotalMarked++
-	b.Islands = append(b.Islands, MakeUnrootedIsland(r, c))
-	b.DiagonalSets = append(b.DiagonalSets, SingleCoordinateSet(Coordinate{r, c}))
-	b.MergeIslands()
-	b.MergeDiagonalSets()
+	b.queueMark(Coordinate{r, c})
+	b.addIsland(Coordinate{r, c}, MakeUnrootedIsland(r, c))
+	b.addDiagonalSet(Coordinate{r, c})
 	i := b.IslandAt(r, c)
+	b.populateUnrootedPossibilities(i)
 	if i.TargetSize > 0 && i.CurrentSize == i.TargetSize {
+		b.recordIsland(i)
 		i.ReadyForBorders = true
 	} else {
 		b.StripPossibilities(i)
 	}
-	//Remove this possibility from all OTHER islands
-	if i.IsRooted() {
-		for _, o := range b.Islands {
-			if !o.IsRooted() || o.Root == i.Root {
-				continue
+	b.stripAround(Coordinate{r, c}, i)
+	return true
+}
+
+// stripAround removes the possibilities of the islands other than i that the
+// newly cleared cell c rules out: those next to c, which would run into it,
+// and, if i is rooted, those of the other rooted islands that contain c.
+// Only the islands listed as reaching c or its neighbours are looked at, in
+// the order of b.Islands.
+func (b *Board) stripAround(c Coordinate, i *Island) {
+	islands := make([]*Island, 0)
+	for _, n := range []Coordinate{c, c.Translate(-1, 0), c.Translate(1, 0), c.Translate(0, -1), c.Translate(0, 1)} {
+		if !b.IsInBounds(n) {
+			continue
+		}
+		for _, o := range b.reachers[b.cellIndex(n)] {
+			if o = b.currentIsland(o); o != i && !islandIn(islands, o) {
+				islands = append(islands, o)
 			}
-			for idx := 0; idx < len(o.Possibilities); idx++ {
-				if o.Possibilities[idx].Contains(Coordinate{r, c}) {
-					RemoveFromSlice(&o.Possibilities, idx)
-					idx--
-				}
+		}
+	}
+	sort.Slice(islands, func(x, y int) bool {
+		return b.islandCells.index(b.cellIndex(islands[x].Members.First())) < b.islandCells.index(b.cellIndex(islands[y].Members.First()))
+	})
+	for _, o := range islands {
+		taken := i.IsRooted() && o.IsRooted()
+		for idx := 0; idx < len(o.Possibilities); idx++ {
+			p := o.Possibilities[idx]
+			if (taken && p.Contains(c)) || (!o.IsComplete() && p.BordersCoordinate(c)) {
+				b.removePossibility(o, idx)
+				idx--
 			}
 		}
 	}
-	return true
+}
+
+// addReachers lists i as reaching every cell of possibilities.
+func (b *Board) addReachers(i *Island, possibilities []*CoordinateSet) {
+	cells := EmptyCoordinateSet()
+	for _, p := range possibilities {
+		cells.AddAll(p)
+	}
+	for _, c := range cells.ToSlice() {
+		k := b.cellIndex(c)
+		if islandIn(b.reachers[k], i) {
+			continue
+		}
+		saved := b.reachers[k]
+		b.trail.record(func() { b.reachers[k] = saved })
+		b.reachers[k] = append(b.reachers[k], i)
+	}
+}
+
+// currentIsland returns the island i is part of now, which is i itself
+// unless i has been merged into another.
+func (b *Board) currentIsland(i *Island) *Island {
+	return b.islandCells.get(b.cellIndex(i.Members.First()))
+}
+
+func islandIn(islands []*Island, i *Island) bool {
+	for _, o := range islands {
+		if o == i {
+			return true
+		}
+	}
+	return false
 }
 
 func (b *Board) Mark(r int, c int, cell Cell) bool {
@@ -638,6 +480,20 @@ func (b *Board) Mark(r int, c int, cell Cell) bool {
 	return false
 }
 
+// markInput marks the cell at (r, c) as cell for a grid being read, failing
+// rather than overwriting the cell if a clue or an earlier mark already gave
+// it the other colour.
+func (b *Board) markInput(r int, c int, cell Cell) error {
+	if cell == UNKNOWN || b.Grid[r][c] == cell {
+		return nil
+	}
+	if b.Grid[r][c] != UNKNOWN {
+		return fmt.Errorf("cell %v is already %s and can't be %s", Coordinate{r, c}, cellName(b.Grid[r][c]), cellName(cell))
+	}
+	b.Mark(r, c, cell)
+	return nil
+}
+
 func (b *Board) MarkPainted(r int, c int) bool {
 	if !b.AreInBounds(r, c) {
 		//TODO: error?
@@ -646,10 +502,11 @@ func (b *Board) MarkPainted(r int, c int) bool {
 	if b.Grid[r][c] == PAINTED {
 		return false
 	}
+	b.recordCell(r, c)
 	b.Grid[r][c] = PAINTED
 	b.TotalMarked++
-	b.WallIslands = append(b.WallIslands, MakeWallIsland(r, c))
-	b.MergeWallIslands()
+	b.queueMark(Coordinate{r, c})
+	b.addWallIsland(Coordinate{r, c}, MakeWallIsland(r, c))
 	b.RemoveFromPossibilities(Coordinate{r, c})
 	return true
 }
@@ -672,6 +529,13 @@ func (b *Board) CharAt(r int, c int) string {
 }
 
 func (b *Board) String() string {
+	if b.Problem.NeedsTokens() {
+		s := b.TokenString()
+		if b.TotalMarked < b.Problem.Size {
+			s += fmt.Sprintf("Total marked: %d\n", b.TotalMarked)
+		}
+		return s
+	}
 	s := ""
 	for ri, row := range b.Grid {
 		for ci := range row {
@@ -685,6 +549,38 @@ func (b *Board) String() string {
 	return s
 }
 
+// TokenString writes the grid with space-separated cells, clues as integers
+// and every column padded to the same width.
+func (b *Board) TokenString() string {
+	clues := make(map[Coordinate]int, len(b.Problem.IslandSpecs))
+	width := 1
+	for _, spec := range b.Problem.IslandSpecs {
+		clues[Coordinate{spec.Row, spec.Col}] = spec.Size
+		if w := len(fmt.Sprint(spec.Size)); w > width {
+			width = w
+		}
+	}
+	var sb strings.Builder
+	for ri, row := range b.Grid {
+		for ci, cell := range row {
+			tok := "_"
+			if sz, ok := clues[Coordinate{ri, ci}]; ok {
+				tok = fmt.Sprint(sz)
+			} else if cell == PAINTED {
+				tok = "X"
+			} else if cell == CLEAR {
+				tok = "."
+			}
+			if ci > 0 {
+				sb.WriteString(" ")
+			}
+			sb.WriteString(fmt.Sprintf("%*s", width, tok))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
 func (b *Board) IsInBounds(c Coordinate) bool {
 	return c.Row >= 0 && c.Col >= 0 && c.Row < b.Problem.Height && c.Col < b.Problem.Width
 }
@@ -730,7 +626,7 @@ func (b *Board) StringDebug() string {
 
 func (b *Board) PlusMyNeighbors(c *CoordinateSet) *CoordinateSet {
 	rset := c.Copy()
-	for m := range c.Map {
+	for _, m := range c.ToSlice() {
 		for dx := -1; dx < 2; dx += 2 {
 			newCoord := m.Translate(dx, 0)
 			if b.IsInBounds(newCoord) {
@@ -747,7 +643,7 @@ func (b *Board) PlusMyNeighbors(c *CoordinateSet) *CoordinateSet {
 }
 
 func (b *Board) HasNeighborWith(c *CoordinateSet, val Cell) bool {
-	for m := range c.Map {
+	for _, m := range c.ToSlice() {
 		for dx := -1; dx < 2; dx += 2 {
 			newCoord := m.Translate(dx, 0)
 			if b.IsInBounds(newCoord) && b.Get(newCoord) == val {
@@ -767,8 +663,8 @@ func (b *Board) HasNeighborWith(c *CoordinateSet, val Cell) bool {
 }
 
 func (b *Board) NeighborsWith(c *CoordinateSet, val Cell) *CoordinateSet {
-	rset := EmptyCoordinateSetSz(c.Size() * 3)
-	for m := range c.Map {
+	rset := EmptyCoordinateSetSz(b.Problem.Height)
+	for _, m := range c.ToSlice() {
 		for dx := -1; dx < 2; dx += 2 {
 			newCoord := m.Translate(dx, 0)
 			if b.IsInBounds(newCoord) && b.Get(newCoord) == val {
@@ -785,7 +681,7 @@ func (b *Board) NeighborsWith(c *CoordinateSet, val Cell) *CoordinateSet {
 }
 
 func (b *Board) TouchesABorder(cs *CoordinateSet) bool {
-	for k := range cs.Map {
+	for _, k := range cs.ToSlice() {
 		if k.Row == 0 || k.Col == 0 || k.Row == b.Problem.Height-1 || k.Col == b.Problem.Width-1 {
 			return true
 		}
@@ -825,17 +721,25 @@ func (b *Board) IsSolved() (bool, error) {
 	for _, i := range b.Islands {
 		if i.CurrentSize != i.TargetSize {
 			coord := Coordinate{}
-			for k := range i.Members.Map {
+			for _, k := range i.Members.ToSlice() {
 				coord = k
 				break
 			}
 			return false, fmt.Errorf("island at %v has size %d (should be %d)", coord, i.CurrentSize, i.TargetSize)
 		}
+		if b.CountNumberedIslands(i.Members) > 1 {
+			return false, fmt.Errorf("island at %v contains more than one number", i.Root)
+		}
 	}
 	return true, nil
 }
 
+// Liberties returns the unknown cells next to i. For islands on b this is
+// i.Liberties, which must not be modified.
 func (b *Board) Liberties(i *Island) *CoordinateSet {
+	if i.Liberties != nil {
+		return i.Liberties
+	}
 	return b.NeighborsWith(i.Members, UNKNOWN)
 }
 
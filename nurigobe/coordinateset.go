@@ -0,0 +1,352 @@
+package nurigobe
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// CoordinateSet is a set of coordinates with non-negative rows and columns,
+// stored as a bitset keyed by row*64*stride+col: row r takes up stride
+// consecutive words, and column c is bit c%64 of word c/64 of its row, so on
+// boards up to 64 columns wide every row is a single word. The stride grows
+// when a wider column is added, and the set grows a row at a time.
+//
+// The key is not row*Width+col, for two reasons. Sets are made without a
+// board (EmptyCoordinateSet, SingleCoordinateSet and the island
+// constructors take no dimensions), so there is no Width to key by. And with
+// whole rows in whole words, the neighbours of a row are one shift of the
+// same word and the words of the rows above and below, where a packed key
+// would split rows across words and need masked shifts at every row edge.
+type CoordinateSet struct {
+	words  []uint64
+	stride int
+}
+
+func EmptyCoordinateSet() *CoordinateSet {
+	return &CoordinateSet{nil, 1}
+}
+
+// EmptyCoordinateSetSz returns an empty set with room for sz rows of up to
+// 64 columns, so that adding members in them doesn't reallocate.
+func EmptyCoordinateSetSz(sz int) *CoordinateSet {
+	return &CoordinateSet{make([]uint64, 0, sz), 1}
+}
+
+func SingleCoordinateSet(c Coordinate) *CoordinateSet {
+	cs := EmptyCoordinateSet()
+	cs.Add(c)
+	return cs
+}
+
+func (s *CoordinateSet) rows() int {
+	return len(s.words) / s.stride
+}
+
+// word returns word w of row r, or 0 if the set doesn't reach that far.
+func (s *CoordinateSet) word(r int, w int) uint64 {
+	if r < 0 || w < 0 || w >= s.stride || r*s.stride >= len(s.words) {
+		return 0
+	}
+	return s.words[r*s.stride+w]
+}
+
+// widen lays the set out again with the given number of words per row.
+func (s *CoordinateSet) widen(stride int) {
+	if stride <= s.stride {
+		return
+	}
+	words := make([]uint64, s.rows()*stride)
+	for r := 0; r < s.rows(); r++ {
+		copy(words[r*stride:], s.words[r*s.stride:(r+1)*s.stride])
+	}
+	s.words, s.stride = words, stride
+}
+
+// grow makes room for rows rows.
+func (s *CoordinateSet) grow(rows int) {
+	for len(s.words) < rows*s.stride {
+		s.words = append(s.words, 0)
+	}
+}
+
+func (s *CoordinateSet) Size() int {
+	n := 0
+	for _, w := range s.words {
+		n += bits.OnesCount64(w)
+	}
+	return n
+}
+
+func (s *CoordinateSet) IsEmpty() bool {
+	for _, w := range s.words {
+		if w != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *CoordinateSet) Add(c Coordinate) {
+	s.widen(c.Col/64 + 1)
+	s.grow(c.Row + 1)
+	s.words[c.Row*s.stride+c.Col/64] |= 1 << uint(c.Col%64)
+}
+
+func (s *CoordinateSet) Del(c Coordinate) {
+	if s.Contains(c) {
+		s.words[c.Row*s.stride+c.Col/64] &^= 1 << uint(c.Col%64)
+	}
+}
+
+func (s *CoordinateSet) DelAll(other *CoordinateSet) {
+	for r := 0; r < s.rows(); r++ {
+		for w := 0; w < s.stride; w++ {
+			s.words[r*s.stride+w] &^= other.word(r, w)
+		}
+	}
+}
+
+func (cs *CoordinateSet) AddAll(other *CoordinateSet) {
+	cs.widen(other.stride)
+	cs.grow(other.rows())
+	for r := 0; r < other.rows(); r++ {
+		for w := 0; w < other.stride; w++ {
+			cs.words[r*cs.stride+w] |= other.words[r*other.stride+w]
+		}
+	}
+}
+
+func (s *CoordinateSet) Plus(other *CoordinateSet) *CoordinateSet {
+	cs := s.Copy()
+	cs.AddAll(other)
+	return cs
+}
+
+func (s *CoordinateSet) Minus(other *CoordinateSet) *CoordinateSet {
+	cs := s.Copy()
+	cs.DelAll(other)
+	return cs
+}
+
+func (s *CoordinateSet) Contains(c Coordinate) bool {
+	if c.Col < 0 {
+		return false
+	}
+	return s.word(c.Row, c.Col/64)&(1<<uint(c.Col%64)) != 0
+}
+
+func (s *CoordinateSet) ContainsAtLeastOne(other *CoordinateSet) bool {
+	for r := 0; r < s.rows(); r++ {
+		for w := 0; w < s.stride; w++ {
+			if s.words[r*s.stride+w]&other.word(r, w) != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ToSlice returns the members in row-major order.
+func (s *CoordinateSet) ToSlice() []Coordinate {
+	out := make([]Coordinate, 0, s.Size())
+	for i, w := range s.words {
+		for w != 0 {
+			b := bits.TrailingZeros64(w)
+			out = append(out, Coordinate{i / s.stride, (i%s.stride)*64 + b})
+			w &= w - 1
+		}
+	}
+	return out
+}
+
+func (s *CoordinateSet) Copy() *CoordinateSet {
+	cs := CoordinateSet{make([]uint64, len(s.words)), s.stride}
+	copy(cs.words, s.words)
+	return &cs
+}
+
+func (s *CoordinateSet) OneMember() Coordinate {
+	return s.First()
+}
+
+func (s *CoordinateSet) BordersCoordinate(c Coordinate) bool {
+	if s.Contains(c) {
+		return false
+	}
+	return s.Contains(c.Translate(-1, 0)) || s.Contains(c.Translate(1, 0)) || s.Contains(c.Translate(0, -1)) || s.Contains(c.Translate(0, 1))
+}
+
+func (cs *CoordinateSet) Equals(other *CoordinateSet) bool {
+	return cs.ContainsAll(other) && other.ContainsAll(cs)
+}
+
+func (superset *CoordinateSet) ContainsAll(subset *CoordinateSet) bool {
+	for r := 0; r < subset.rows(); r++ {
+		for w := 0; w < subset.stride; w++ {
+			if subset.words[r*subset.stride+w]&^superset.word(r, w) != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// shiftedWord returns word w of row r with every member moved one column
+// right (dc = 1) or left (dc = -1).
+func (s *CoordinateSet) shiftedWord(r int, w int, dc int) uint64 {
+	if dc > 0 {
+		return s.word(r, w)<<1 | s.word(r, w-1)>>63
+	}
+	return s.word(r, w)>>1 | s.word(r, w+1)<<63
+}
+
+// BordersSet reports whether any member of cs is orthogonally adjacent to a
+// member of other.
+func (cs *CoordinateSet) BordersSet(other *CoordinateSet) bool {
+	for r := 0; r < cs.rows(); r++ {
+		for w := 0; w < cs.stride; w++ {
+			neighbors := other.shiftedWord(r, w, 1) | other.shiftedWord(r, w, -1) | other.word(r-1, w) | other.word(r+1, w)
+			if cs.words[r*cs.stride+w]&neighbors != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// BordersSetDiagonally reports whether any member of cs is within one row
+// and one column of a member of other, as AreDiagonallyAdjacent does.
+func (cs *CoordinateSet) BordersSetDiagonally(other *CoordinateSet) bool {
+	for r := 0; r < cs.rows(); r++ {
+		for w := 0; w < cs.stride; w++ {
+			neighbors := uint64(0)
+			for dr := -1; dr < 2; dr++ {
+				neighbors |= other.word(r+dr, w) | other.shiftedWord(r+dr, w, 1) | other.shiftedWord(r+dr, w, -1)
+			}
+			if cs.words[r*cs.stride+w]&neighbors != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *CoordinateSet) CanAddWall(c Coordinate) bool {
+	/**
+	 * Diagram for the algorithm below:
+	 *
+	 * n is a slice containing flags for whether neighbors are painted.
+	 * The asterisk represents c, and the digits represent indexes into n
+	 * indicating whether that cell is painted.
+	 *
+	 *  012
+	 *  3*4
+	 *  567
+	 *
+	**/
+
+	n := make([]bool, 8)
+	idx := 0
+	for dr := -1; dr < 2; dr++ {
+		for dc := -1; dc < 2; dc++ {
+			if dr == 0 && dc == 0 {
+				continue
+			}
+			n[idx] = s.Contains(c.Translate(dr, dc))
+			idx++
+		}
+	}
+	//Maybe the compiler is smart enough to optimize these conditionals for us;
+	//just in case, I'll do it manually
+	if n[1] {
+		if n[0] && n[3] {
+			return false
+		}
+		if n[2] && n[4] {
+			return false
+		}
+	}
+	if n[6] {
+		if n[3] && n[5] {
+			return false
+		}
+		if n[4] && n[7] {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *CoordinateSet) String() string {
+	out := ""
+	for _, m := range s.ToSlice() {
+		out += fmt.Sprintf("(r%d, c%d) ", m.Row, m.Col)
+	}
+	return out
+}
+
+// SortedString is like String but lists the members in row-major order.
+func (s *CoordinateSet) SortedString() string {
+	names := make([]string, 0, s.Size())
+	for _, c := range s.ToSlice() {
+		names = append(names, fmt.Sprintf("(r%d,c%d)", c.Row, c.Col))
+	}
+	return strings.Join(names, " ")
+}
+
+// First returns the top-left-most member, or NilCoordinate() if the set is
+// empty.
+func (s *CoordinateSet) First() Coordinate {
+	for i, w := range s.words {
+		if w != 0 {
+			return Coordinate{i / s.stride, (i%s.stride)*64 + bits.TrailingZeros64(w)}
+		}
+	}
+	return NilCoordinate()
+}
+
+// Hash returns a hash of the members that doesn't depend on the stride, so
+// equal sets always hash alike.
+func (s *CoordinateSet) Hash() uint64 {
+	const prime = 1099511628211
+	h := uint64(14695981039346656037)
+	for i, w := range s.words {
+		if w == 0 {
+			continue
+		}
+		h = (h ^ uint64(i/s.stride)<<32 ^ uint64(i%s.stride)) * prime
+		h = (h ^ w) * prime
+	}
+	return h
+}
+
+// CoordinateSetSet is a set of CoordinateSets, bucketed by their hashes.
+type CoordinateSetSet struct {
+	buckets map[uint64][]*CoordinateSet
+}
+
+func EmptyCoordinateSetSet() *CoordinateSetSet {
+	return &CoordinateSetSet{make(map[uint64][]*CoordinateSet)}
+}
+
+// Add adds a copy of cs and reports whether it wasn't already present.
+func (css *CoordinateSetSet) Add(cs *CoordinateSet) bool {
+	h := cs.Hash()
+	for _, other := range css.buckets[h] {
+		if other.Equals(cs) {
+			return false
+		}
+	}
+	css.buckets[h] = append(css.buckets[h], cs.Copy())
+	return true
+}
+
+func (css *CoordinateSetSet) Contains(cs *CoordinateSet) bool {
+	for _, other := range css.buckets[cs.Hash()] {
+		if other.Equals(cs) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package nurigobe
+
+import "testing"
+
+// mapSet is the map-based CoordinateSet the bitset replaced, kept here so
+// the benchmarks can compare the two.
+type mapSet map[Coordinate]bool
+
+func (s mapSet) plus(other mapSet) mapSet {
+	cs := make(mapSet, len(s)+len(other))
+	for k := range s {
+		cs[k] = true
+	}
+	for k := range other {
+		cs[k] = true
+	}
+	return cs
+}
+
+func (s mapSet) bordersSet(other mapSet) bool {
+	for m := range s {
+		for _, n := range []Coordinate{m.Translate(-1, 0), m.Translate(1, 0), m.Translate(0, -1), m.Translate(0, 1)} {
+			if other[n] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// benchmarkSets returns two island-sized sets in the corners of a 10x10
+// board, which don't border each other, in both representations.
+func benchmarkSets() (*CoordinateSet, *CoordinateSet, mapSet, mapSet) {
+	a, b := EmptyCoordinateSet(), EmptyCoordinateSet()
+	ma, mb := make(mapSet), make(mapSet)
+	for _, c := range []Coordinate{{0, 0}, {0, 1}, {1, 1}, {2, 1}, {2, 2}, {3, 2}} {
+		a.Add(c)
+		ma[c] = true
+		o := Coordinate{9 - c.Row, 9 - c.Col}
+		b.Add(o)
+		mb[o] = true
+	}
+	return a, b, ma, mb
+}
+
+func BenchmarkCoordinateSetBordersSet(b *testing.B) {
+	x, y, mx, my := benchmarkSets()
+	b.Run("bitset", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			x.BordersSet(y)
+		}
+	})
+	b.Run("map", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			mx.bordersSet(my)
+		}
+	})
+}
+
+func BenchmarkCoordinateSetPlus(b *testing.B) {
+	x, y, mx, my := benchmarkSets()
+	b.Run("bitset", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			x.Plus(y)
+		}
+	})
+	b.Run("map", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			mx.plus(my)
+		}
+	})
+}
+
+func BenchmarkCoordinateSetContains(b *testing.B) {
+	x, _, mx, _ := benchmarkSets()
+	c := Coordinate{2, 2}
+	b.Run("bitset", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			x.Contains(c)
+		}
+	})
+	b.Run("map", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			_ = mx[c]
+		}
+	})
+}
+
+func TestEmptyCoordinateSetSz(t *testing.T) {
+	cs := EmptyCoordinateSetSz(4)
+	before := cap(cs.words)
+	cs.Add(Coordinate{3, 5})
+	if before < 4 || cap(cs.words) != before || !cs.Contains(Coordinate{3, 5}) {
+		t.Fatalf("got capacity %d before adding and %d after, want 4 or more and no change", before, cap(cs.words))
+	}
+}
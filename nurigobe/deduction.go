@@ -0,0 +1,115 @@
+package nurigobe
+
+// Rule names one of the solver's deduction techniques.
+type Rule string
+
+const (
+	RuleInitialize                  Rule = "InitSolve"
+	RuleAddIslandBorders            Rule = "AddIslandBorders"
+	RuleExtendIslandsOneLiberty     Rule = "ExtendIslandsOneLiberty"
+	RuleExtendWallIslandsOneLiberty Rule = "ExtendWallIslandsOneLiberty"
+	RulePaintTwoBorderedCells       Rule = "PaintTwoBorderedCells"
+	RuleExtendWallIslands           Rule = "ExtendWallIslands"
+	RuleFillElbows                  Rule = "FillElbows"
+	RulePaintUnreachables           Rule = "PaintUnreachables"
+	RuleStripPossibilities          Rule = "StripPossibilities"
+	RuleFillIslandNecessaries       Rule = "FillIslandNecessaries"
+	RuleFindSinglePoolPreventers    Rule = "FindSinglePoolPreventers"
+	RuleConnectUnrootedIslands      Rule = "ConnectUnrootedIslands"
+	RuleEliminateWallSplitters      Rule = "EliminateWallSplitters"
+	RuleEliminateIntolerables       Rule = "EliminateIntolerables"
+	RuleShallowGuess                Rule = "MakeAGuess (shallow)"
+	RuleDeepGuess                   Rule = "MakeAGuess (deep)"
+)
+
+// Deduction is a single step taken by the solver: either a cell being marked
+// or, if Coord is nil, some islands losing possibilities.
+type Deduction struct {
+	Rule Rule
+	// Coord is the cell that was marked, or NilCoordinate() if the rule only
+	// pruned island possibilities.
+	Coord Coordinate
+	// Cell is PAINTED or CLEAR, or UNKNOWN for pruning.
+	Cell Cell
+	// Supporting holds the cells that justify the deduction, e.g. the members
+	// of the islands bordering a two-bordered cell.
+	Supporting *CoordinateSet
+	// Islands describes the islands involved, as they were before the mark.
+	Islands []IslandRef
+	// Contradiction is the error found when the opposite colour was guessed.
+	Contradiction string
+	// Pruned is the number of possibilities removed by a pruning deduction.
+	Pruned int
+}
+
+func (d *Deduction) IsPruning() bool {
+	return d.Coord.IsNil()
+}
+
+// possibilityCounts records how many possibilities each island has so that
+// pruning can be detected after a rule runs.
+func (b *Board) possibilityCounts() map[*Island]int {
+	counts := make(map[*Island]int, len(b.Islands))
+	for _, i := range b.Islands {
+		counts[i] = len(i.Possibilities)
+	}
+	return counts
+}
+
+func (b *Board) pruningDeduction(rule Rule, before map[*Island]int) *Deduction {
+	d := &Deduction{Rule: rule, Coord: NilCoordinate(), Cell: UNKNOWN, Supporting: EmptyCoordinateSet()}
+	for _, i := range b.Islands {
+		oldLen, ok := before[i]
+		if !ok || oldLen <= len(i.Possibilities) {
+			continue
+		}
+		d.Pruned += oldLen - len(i.Possibilities)
+		d.Supporting.AddAll(i.Members)
+		d.Islands = append(d.Islands, i.Ref())
+	}
+	return d
+}
+
+// NextDeduction propagates the earlier marks and runs the rules in the order
+// AutoSolve uses them, but stops as soon as one of them marks a cell or
+// prunes a possibility. The mark is applied to the solver's board and
+// returned; nil means the solver is stuck or its context was cancelled,
+// since possibilities enumerated under a cancelled context are incomplete.
+func (s *Solver) NextDeduction() *Deduction {
+	if s.Cancelled() {
+		return nil
+	}
+	s.stepping = true
+	s.next = nil
+	defer func() {
+		s.stepping = false
+		s.next = nil
+	}()
+	if !s.initialized {
+		s.InitSolve()
+		if s.next != nil {
+			//marked before enumerating possibilities, so it stands
+			return s.next
+		}
+		if s.Cancelled() {
+			return nil
+		}
+	}
+	if s.b.TotalMarked == s.b.Problem.Size || s.b.ContainsError() != nil {
+		return nil
+	}
+	if s.propagate(); s.next != nil {
+		return s.next
+	}
+	if s.b.TotalMarked == s.b.Problem.Size || s.b.ContainsError() != nil {
+		return nil
+	}
+	before := s.b.possibilityCounts()
+	if !s.solveStep(true, false) {
+		return nil
+	}
+	if s.next != nil {
+		return s.next
+	}
+	return s.b.pruningDeduction(s.rule, before)
+}
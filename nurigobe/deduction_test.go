@@ -0,0 +1,24 @@
+package nurigobe
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNextDeductionCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	b := readProblem(t, "problem1.txt")
+	before := b.String()
+	s := NewSolverWithContext(ctx, b)
+	s.Progress = nil
+	if d := s.NextDeduction(); d != nil {
+		t.Fatalf("got deduction %+v from a cancelled solver", d)
+	}
+	if b.String() != before {
+		t.Fatalf("cancelled solver marked the board:\n%v", b)
+	}
+	if d, err := NewGame(ctx, readProblem(t, "problem1.txt")).Hint(ctx); err != context.Canceled {
+		t.Fatalf("got hint %+v, error %v; want %v", d, err, context.Canceled)
+	}
+}
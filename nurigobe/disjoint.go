@@ -0,0 +1,181 @@
+package nurigobe
+
+// cellSets partitions a board's added cells, indexed row*Width+col, into
+// sets, each belonging to a value (an island or a diagonal set) on one of
+// the board's lists. Every cell names its set directly, so looking a cell up
+// takes two reads; merging relabels the cells of the smaller set, so a cell
+// is relabelled at most log n times, and swaps the merged-away value out of
+// its list in place.
+type cellSets[T comparable] struct {
+	// set names the set of each added cell by one of its cells, or is -1.
+	set []int
+	// next links the cells of each set into a ring.
+	next []int
+	// size, owner and pos are indexed by set: its number of cells, its
+	// value and the value's index in the list.
+	size  []int
+	owner []T
+	pos   []int
+	// sets holds the set of each value in the list, in the same order.
+	sets []int
+}
+
+func newCellSets[T comparable](n int) *cellSets[T] {
+	cs := &cellSets[T]{make([]int, n), make([]int, n), make([]int, n), make([]T, n), make([]int, n), nil}
+	for i := range cs.set {
+		cs.set[i] = -1
+	}
+	return cs
+}
+
+// save records cell i's entries on t, to be restored on rollback.
+func (cs *cellSets[T]) save(t *trail, i int) {
+	if !t.recording() {
+		return
+	}
+	set, next, size, owner, pos := cs.set[i], cs.next[i], cs.size[i], cs.owner[i], cs.pos[i]
+	t.record(func() {
+		cs.set[i], cs.next[i], cs.size[i], cs.owner[i], cs.pos[i] = set, next, size, owner, pos
+	})
+}
+
+// add makes cell i a set of its own, belonging to v, which is appended to
+// list.
+func (cs *cellSets[T]) add(t *trail, list *[]T, i int, v T) {
+	cs.save(t, i)
+	saved, sets := *list, cs.sets
+	t.record(func() { *list, cs.sets = saved, sets })
+	cs.set[i] = i
+	cs.next[i] = i
+	cs.size[i] = 1
+	cs.owner[i] = v
+	cs.pos[i] = len(*list)
+	*list = append(*list, v)
+	cs.sets = append(cs.sets, i)
+}
+
+// get returns the value cell i's set belongs to, or the zero value if i
+// hasn't been added.
+func (cs *cellSets[T]) get(i int) T {
+	if cs.set[i] < 0 {
+		var zero T
+		return zero
+	}
+	return cs.owner[cs.set[i]]
+}
+
+// index returns the position in the list of the value cell i's set belongs
+// to, or -1 if i hasn't been added.
+func (cs *cellSets[T]) index(i int) int {
+	if cs.set[i] < 0 {
+		return -1
+	}
+	return cs.pos[cs.set[i]]
+}
+
+// remove takes the value at index k out of list by moving the last value
+// into its place.
+func (cs *cellSets[T]) remove(t *trail, list *[]T, k int) {
+	last := len(*list) - 1
+	moved := cs.sets[last]
+	cs.save(t, moved)
+	saved, sets := *list, cs.sets
+	removedValue, removedSet, movedValue := saved[k], sets[k], saved[last]
+	t.record(func() {
+		saved[k], sets[k] = removedValue, removedSet
+		saved[last], sets[last] = movedValue, moved
+		*list, cs.sets = saved, sets
+	})
+	(*list)[k], cs.sets[k] = movedValue, moved
+	cs.pos[moved] = k
+	*list, cs.sets = (*list)[:last], cs.sets[:last]
+}
+
+// merge joins set drop into set keep, whose value has taken in drop's, and
+// removes drop's value from list.
+func (cs *cellSets[T]) merge(t *trail, list *[]T, keep int, drop int) {
+	cs.remove(t, list, cs.pos[drop])
+	v, p := cs.owner[keep], cs.pos[keep]
+	if cs.size[keep] < cs.size[drop] {
+		keep, drop = drop, keep
+	}
+	cs.save(t, keep)
+	for k := drop; ; {
+		cs.save(t, k)
+		cs.set[k] = keep
+		if k = cs.next[k]; k == drop {
+			break
+		}
+	}
+	var zero T
+	cs.next[keep], cs.next[drop] = cs.next[drop], cs.next[keep]
+	cs.size[keep] += cs.size[drop]
+	cs.owner[keep], cs.pos[keep] = v, p
+	cs.owner[drop] = zero
+	if sets, old := cs.sets, cs.sets[p]; old != keep {
+		t.record(func() { sets[p] = old })
+		sets[p] = keep
+	}
+}
+
+// clone copies the sets, replacing each value with remap(value). The list
+// must have been copied in the same order.
+func (cs *cellSets[T]) clone(remap map[T]T) *cellSets[T] {
+	n := len(cs.set)
+	new := &cellSets[T]{make([]int, n), make([]int, n), make([]int, n), make([]T, n), make([]int, n), make([]int, len(cs.sets))}
+	copy(new.set, cs.set)
+	copy(new.next, cs.next)
+	copy(new.size, cs.size)
+	copy(new.pos, cs.pos)
+	copy(new.sets, cs.sets)
+	var zero T
+	for i, v := range cs.owner {
+		if v != zero {
+			new.owner[i] = remap[v]
+		}
+	}
+	return new
+}
+
+// mergeAt joins the set containing c, which must have been added to cells,
+// with the sets of the neighbouring cells that have been, including the
+// diagonal neighbours if diagonal is set. Of the values involved, the one
+// earliest in list takes in the others with absorb, in list order, and they
+// are removed from list.
+func mergeAt[T comparable](b *Board, cells *cellSets[T], list *[]T, c Coordinate, diagonal bool, absorb func(into T, from T)) {
+	group := []int{cells.set[b.cellIndex(c)]}
+	for dr := -1; dr < 2; dr++ {
+		for dc := -1; dc < 2; dc++ {
+			if (dr == 0 && dc == 0) || (!diagonal && dr != 0 && dc != 0) {
+				continue
+			}
+			n := c.Translate(dr, dc)
+			if !b.IsInBounds(n) {
+				continue
+			}
+			set := cells.set[b.cellIndex(n)]
+			if set < 0 {
+				continue
+			}
+			found := false
+			for _, g := range group {
+				found = found || g == set
+			}
+			if !found {
+				group = append(group, set)
+			}
+		}
+	}
+	//in list order, by insertion since the group is at most nine sets
+	for i := 1; i < len(group); i++ {
+		for j := i; j > 0 && cells.pos[group[j]] < cells.pos[group[j-1]]; j-- {
+			group[j], group[j-1] = group[j-1], group[j]
+		}
+	}
+	keep := group[0]
+	for _, drop := range group[1:] {
+		absorb(cells.owner[keep], cells.owner[drop])
+		cells.merge(&b.trail, list, keep, drop)
+		keep = cells.set[keep]
+	}
+}
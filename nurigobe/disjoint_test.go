@@ -0,0 +1,53 @@
+package nurigobe
+
+import "testing"
+
+// islandsAt returns the island at every cell of b, in row-major order.
+func islandsAt(b *Board) []*Island {
+	out := make([]*Island, 0, b.Problem.Size)
+	for r := 0; r < b.Problem.Height; r++ {
+		for c := 0; c < b.Problem.Width; c++ {
+			out = append(out, b.IslandAt(r, c))
+		}
+	}
+	return out
+}
+
+func sameIslands(a []*Island, b []*Island) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMergeAndRollback(t *testing.T) {
+	b := trailBoard(t)
+	cells, islands := islandsAt(b), append([]*Island(nil), b.Islands...)
+	cp := b.Checkpoint()
+	//joins the 5 at r1c2, the 1 at r2c1 and the 2 at r2c3, then grows the result
+	b.MarkClear(2, 2)
+	b.MarkClear(3, 2)
+	merged := b.IslandAt(2, 2)
+	for _, c := range []Coordinate{{1, 2}, {2, 1}, {2, 3}, {3, 2}} {
+		if b.IslandAt(c.Row, c.Col) != merged {
+			t.Fatalf("%v is not in the merged island", c)
+		}
+	}
+	if len(b.Islands) != len(islands)-2 || !islandIn(b.Islands, merged) {
+		t.Fatalf("got %d islands, want %d including the merged one", len(b.Islands), len(islands)-2)
+	}
+	for k, i := range b.Islands {
+		if got := b.islandCells.index(b.cellIndex(i.Members.First())); got != k {
+			t.Fatalf("island %d is recorded at index %d", k, got)
+		}
+	}
+	b.Rollback(cp)
+	if !sameIslands(islandsAt(b), cells) || !sameIslands(b.Islands, islands) {
+		t.Fatalf("rollback didn't restore the islands")
+	}
+}
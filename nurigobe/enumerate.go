@@ -5,11 +5,11 @@ import (
 )
 
 func (s *Solver) FindPossibleIslandsRec(c chan *CoordinateSet, members *CoordinateSet, targetSize int, css *CoordinateSetSet) {
-	if css.Contains(members) {
+	if s.Cancelled() || css.Contains(members) {
 		return
 	}
 	potentialNew := s.b.NeighborsWith(members, UNKNOWN)
-	for p := range potentialNew.Map {
+	for _, p := range potentialNew.ToSlice() {
 		membersNew := members.Copy()
 		membersNew.Add(p)
 		if css.Contains(membersNew) {
@@ -50,17 +50,16 @@ func (s *Solver) FindPossibleIslands(c chan *CoordinateSet, island *Island) {
 }
 
 func (s *Solver) PopulateIslandPossibilities() {
-	Watch.Start("Pop poss")
-	defer Watch.Stop("Pop poss")
+	s.b.Watch.Start("Pop poss")
+	defer s.b.Watch.Stop("Pop poss")
 	for _, island := range s.b.Islands {
-		if len(island.Possibilities) > 0 {
-			island.Possibilities = make([]*CoordinateSet, 0)
-		}
+		possibilities := make([]*CoordinateSet, 0)
 		c := make(chan *CoordinateSet)
 		go s.FindPossibleIslands(c, island)
 		for p := range c {
-			island.Possibilities = append(island.Possibilities, p)
+			possibilities = append(possibilities, p)
 		}
+		s.b.setPossibilities(island, possibilities)
 		island.PopulateReachables()
 	}
 	s.b.PopulateUnrootedPossibilities()
@@ -68,32 +67,38 @@ func (s *Solver) PopulateIslandPossibilities() {
 
 func (b *Board) PopulateUnrootedPossibilities() {
 	for _, island := range b.Islands {
-		if island.IsRooted() {
-			continue
-		}
-		if len(island.Possibilities) > 0 {
-			continue
-		}
-		for _, o := range b.Islands {
-			for _, p := range o.Possibilities {
-				if p.ContainsAll(island.Members) {
-					island.Possibilities = append(island.Possibilities, p)
-				}
+		b.populateUnrootedPossibilities(island)
+	}
+}
+
+// populateUnrootedPossibilities gives island, if it is unrooted and has no
+// possibilities yet, the possibilities of the rooted islands that contain it.
+func (b *Board) populateUnrootedPossibilities(island *Island) {
+	if island.IsRooted() || len(island.Possibilities) > 0 {
+		return
+	}
+	var possibilities []*CoordinateSet
+	for _, o := range b.Islands {
+		for _, p := range o.Possibilities {
+			if p.ContainsAll(island.Members) {
+				possibilities = append(possibilities, p)
 			}
 		}
 	}
+	if len(possibilities) > 0 {
+		b.setPossibilities(island, possibilities)
+	}
 }
 
 func (s *Solver) PopulateAllReachables() {
 	s.UpdateAction("Unreachables")
 	for _, island := range s.b.Islands {
+		s.b.recordIsland(island)
 		island.PopulateReachables()
 	}
 }
 
 func (i *Island) PopulateReachables() {
-	Watch.Start("Reachables")
-	defer Watch.Stop("Reachables")
 	i.Reachable = EmptyCoordinateSet()
 	for _, p := range i.Possibilities {
 		i.Reachable.AddAll(p)
@@ -111,8 +116,8 @@ func (b *Board) StripAllPossibilities() bool {
 }
 
 func (b *Board) StripPossibilities(i *Island) bool {
-	Watch.Start("Strip Poss")
-	defer Watch.Stop("Strip Poss")
+	b.Watch.Start("Strip Poss")
+	defer b.Watch.Stop("Strip Poss")
 	if len(i.Possibilities) == 0 || i.IsComplete() {
 		return false
 	}
@@ -127,7 +132,7 @@ func (b *Board) StripPossibilities(i *Island) bool {
 				continue
 			}
 		}
-		RemoveFromSlice(&i.Possibilities, idx)
+		b.removePossibility(i, idx)
 		idx--
 		changed = true
 	}
@@ -135,8 +140,6 @@ func (b *Board) StripPossibilities(i *Island) bool {
 }
 
 func (i *Island) MustIncludeOne(cs *CoordinateSet) bool {
-	Watch.Start("MustInclude")
-	defer Watch.Stop("MustInclude")
 	if len(i.Possibilities) == 0 {
 		return false
 	}
@@ -151,59 +154,78 @@ func (i *Island) MustIncludeOne(cs *CoordinateSet) bool {
 	return len(i.Possibilities) != oldLen
 }
 
+// mustIncludeOne is MustIncludeOne for an island on b.
+func (b *Board) mustIncludeOne(i *Island, cs *CoordinateSet) bool {
+	b.recordIsland(i)
+	if !i.MustIncludeOne(cs) {
+		return false
+	}
+	b.queuePossibilities(i)
+	return true
+}
+
 func (s *Solver) FillIslandNecessaries() bool {
-	s.UpdateAction("Filling necessaries")
-	Watch.Start("FIN")
-	defer Watch.Stop("FIN")
+	s.BeginRule(RuleFillIslandNecessaries, "Filling necessaries")
+	s.b.Watch.Start("FIN")
+	defer s.b.Watch.Stop("FIN")
 	didChange := false
-	ct := 0
 	for _, i := range s.b.Islands {
-		ct++
-		if i.CurrentSize >= i.TargetSize {
+		didChange = s.fillNecessariesFor(i) || didChange
+	}
+	return didChange
+}
+
+// fillNecessariesFor clears the cells in all of i's possibilities and paints
+// the cells bordering all of them.
+func (s *Solver) fillNecessariesFor(i *Island) bool {
+	if i.CurrentSize >= i.TargetSize {
+		return false
+	}
+	didChange := false
+	var necessary *CoordinateSet = nil
+	var necessaryNeighbors *CoordinateSet = nil
+	for _, p := range i.Possibilities {
+		if necessary == nil {
+			necessary = p.Copy()
+			necessaryNeighbors = s.b.NeighborsWith(necessary, UNKNOWN)
+			necessary.DelAll(i.Members)
 			continue
 		}
-		var necessary *CoordinateSet = nil
-		var necessaryNeighbors *CoordinateSet = nil
-		for _, p := range i.Possibilities {
-			if necessary == nil {
-				necessary = p.Copy()
-				necessaryNeighbors = s.b.NeighborsWith(necessary, UNKNOWN)
-				necessary.DelAll(i.Members)
-				continue
-			}
-			for cell := range necessary.Map {
-				if !p.Contains(cell) {
-					necessary.Del(cell)
-				}
-			}
-			for cell := range necessaryNeighbors.Map {
-				if !p.BordersCoordinate(cell) {
-					necessaryNeighbors.Del(cell)
-				}
-			}
-			if necessary.IsEmpty() && necessaryNeighbors.IsEmpty() {
-				break
+		for _, cell := range necessary.ToSlice() {
+			if !p.Contains(cell) {
+				necessary.Del(cell)
 			}
 		}
-		if necessary != nil {
-			for target := range necessary.Map {
-				didChange = s.MarkClear(target.Row, target.Col) || didChange
-			}
-			for target := range necessaryNeighbors.Map {
-				didChange = s.MarkPainted(target.Row, target.Col) || didChange
+		for _, cell := range necessaryNeighbors.ToSlice() {
+			if !p.BordersCoordinate(cell) {
+				necessaryNeighbors.Del(cell)
 			}
 		}
+		if necessary.IsEmpty() && necessaryNeighbors.IsEmpty() {
+			break
+		}
+	}
+	if necessary != nil {
+		if !necessary.IsEmpty() || !necessaryNeighbors.IsEmpty() {
+			s.useRule(RuleFillIslandNecessaries, "Filling necessaries")
+		}
+		for _, target := range necessary.ToSlice() {
+			didChange = s.MarkBecause(target.Row, target.Col, CLEAR, i.Members, i) || didChange
+		}
+		for _, target := range necessaryNeighbors.ToSlice() {
+			didChange = s.MarkBecause(target.Row, target.Col, PAINTED, i.Members, i) || didChange
+		}
 	}
 	return didChange
 }
 
 func (b *Board) RemoveFromPossibilities(newlyPainted Coordinate) {
-	Watch.Start("RemoveFromPossibilities")
-	defer Watch.Stop("RemoveFromPossibilities")
+	b.Watch.Start("RemoveFromPossibilities")
+	defer b.Watch.Stop("RemoveFromPossibilities")
 	for _, i := range b.Islands {
 		for idx := 0; idx < len(i.Possibilities); idx++ {
 			if i.Possibilities[idx].Contains(newlyPainted) {
-				RemoveFromSlice(&i.Possibilities, idx)
+				b.removePossibility(i, idx)
 				idx--
 			}
 		}
@@ -212,14 +234,14 @@ func (b *Board) RemoveFromPossibilities(newlyPainted Coordinate) {
 
 func (s *Solver) PaintUnreachables() bool {
 	s.PopulateAllReachables()
-	s.UpdateAction("Painting unreachables")
-	Watch.Start("PaintUnreachables")
-	defer Watch.Stop("PaintUnreachables")
+	s.BeginRule(RulePaintUnreachables, "Painting unreachables")
+	s.b.Watch.Start("PaintUnreachables")
+	defer s.b.Watch.Stop("PaintUnreachables")
 	const UNREACHABLE = 0
 	const REACHABLE = 1
 	s.b.ClearScratchGrid()
 	for _, i := range s.b.Islands {
-		for r := range i.Reachable.Map {
+		for _, r := range i.Reachable.ToSlice() {
 			s.b.ScratchGrid[r.Row][r.Col] = REACHABLE
 		}
 	}
@@ -234,6 +256,21 @@ func (s *Solver) PaintUnreachables() bool {
 	return changed
 }
 
+// paintIfUnreachable paints c if it is unknown and outside every island's
+// reachable cells.
+func (s *Solver) paintIfUnreachable(c Coordinate) bool {
+	if s.b.Get(c) != UNKNOWN {
+		return false
+	}
+	for _, i := range s.b.Islands {
+		if i.Reachable != nil && i.Reachable.Contains(c) {
+			return false
+		}
+	}
+	s.useRule(RulePaintUnreachables, "Painting unreachables")
+	return s.MarkPainted(c.Row, c.Col)
+}
+
 func (i *Island) CanReach(target Coordinate) bool {
 	//Unrooted islands will never be the only islands that can reach a cell, so we can skip them
 	if !i.IsRooted() {
@@ -251,9 +288,9 @@ func (i *Island) CanReach(target Coordinate) bool {
 }
 
 func (s *Solver) FindSinglePoolPreventers() bool {
-	s.UpdateAction("Single pool preventers")
-	Watch.Start("Find Pool Preventers")
-	defer Watch.Stop("Find Pool Preventers")
+	s.BeginRule(RuleFindSinglePoolPreventers, "Single pool preventers")
+	s.b.Watch.Start("Find Pool Preventers")
+	defer s.b.Watch.Stop("Find Pool Preventers")
 	didChange := false
 	for r := 0; r < s.b.Problem.Height-1; r++ {
 	onePossiblePool:
@@ -267,6 +304,11 @@ func (s *Solver) FindSinglePoolPreventers() bool {
 						continue onePossiblePool
 					}
 					for _, i := range s.b.Islands {
+						//an unrooted island's cells end up in a rooted
+						//island that can reach them too
+						if !i.IsRooted() {
+							continue
+						}
 						if i.CanReach(c) {
 							if savior != nil && savior != i {
 								continue onePossiblePool
@@ -278,7 +320,7 @@ func (s *Solver) FindSinglePoolPreventers() bool {
 				}
 			}
 			if savior != nil {
-				didChange = savior.MustIncludeOne(cs) || didChange
+				didChange = s.b.mustIncludeOne(savior, cs) || didChange
 			}
 		}
 	}
@@ -286,9 +328,9 @@ func (s *Solver) FindSinglePoolPreventers() bool {
 }
 
 func (s *Solver) ConnectUnrootedIslands() bool {
-	s.UpdateAction("Connect unrooted islands")
-	Watch.Start("Connect Unrooted Islands")
-	defer Watch.Stop("Connect Unrooted Islands")
+	s.BeginRule(RuleConnectUnrootedIslands, "Connect unrooted islands")
+	s.b.Watch.Start("Connect Unrooted Islands")
+	defer s.b.Watch.Stop("Connect Unrooted Islands")
 	didChange := false
 oneUnrootedIsland:
 	for _, i := range s.b.Islands {
@@ -309,16 +351,16 @@ oneUnrootedIsland:
 			}
 		}
 		if savior != nil {
-			didChange = savior.MustIncludeOne(SingleCoordinateSet(mem)) || didChange
+			didChange = s.b.mustIncludeOne(savior, SingleCoordinateSet(mem)) || didChange
 		}
 	}
 	return didChange
 }
 
 func (s *Solver) EliminateWallSplitters() bool {
-	s.UpdateAction("Eliminate wall splitters")
-	Watch.Start("EliminateWallSplitters")
-	defer Watch.Stop("EliminateWallSplitters")
+	s.BeginRule(RuleEliminateWallSplitters, "Eliminate wall splitters")
+	s.b.Watch.Start("EliminateWallSplitters")
+	defer s.b.Watch.Stop("EliminateWallSplitters")
 	changed := false
 	for _, i := range s.b.Islands {
 		for idx := 0; idx < len(i.Possibilities); idx++ {
@@ -331,7 +373,7 @@ func (s *Solver) EliminateWallSplitters() bool {
 				}
 			}
 			if eliminate {
-				RemoveFromSlice(&i.Possibilities, idx)
+				s.b.removePossibility(i, idx)
 				idx--
 				changed = true
 			}
@@ -345,7 +387,7 @@ onePossibility:
 	for _, p := range i.Possibilities {
 		ctYes := 0
 		ctNo := 0
-		for c := range p.Map {
+		for _, c := range p.ToSlice() {
 			if cs.Contains(c) {
 				ctYes += 1
 			} else {
@@ -374,9 +416,9 @@ onePossibility:
 }
 
 func (s *Solver) EliminateIntolerables() bool {
-	s.UpdateAction("Eliminating intolerable possibilities")
-	Watch.Start("Eliminate Intolerables")
-	defer Watch.Stop("Eliminate Intolerables")
+	s.BeginRule(RuleEliminateIntolerables, "Eliminating intolerable possibilities")
+	s.b.Watch.Start("Eliminate Intolerables")
+	defer s.b.Watch.Stop("Eliminate Intolerables")
 	didChange := false
 	for _, i := range s.b.Islands {
 		if i.TargetSize <= i.CurrentSize {
@@ -398,7 +440,7 @@ func (s *Solver) EliminateIntolerables() bool {
 				}
 			}
 			if intolerable {
-				RemoveFromSlice(&i.Possibilities, idx)
+				s.b.removePossibility(i, idx)
 				idx--
 				didChange = true
 			}
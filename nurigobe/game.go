@@ -0,0 +1,185 @@
+package nurigobe
+
+import (
+	"context"
+	"fmt"
+)
+
+// Move is a single change made to a Game's grid.
+type Move struct {
+	Coord Coordinate
+	From  Cell
+	To    Cell
+}
+
+// Game is a puzzle being solved by a person. Unlike a Board, its grid can be
+// changed freely, including back to UNKNOWN, and every change can be undone.
+type Game struct {
+	Problem  ProblemDef
+	Grid     [][]Cell
+	clues    *CoordinateSet
+	solution *Board
+	undo     []Move
+	redo     []Move
+}
+
+// NewGame starts a game from b's current grid. The puzzle is solved up front
+// so that mistakes can be pointed out; if the solver can't finish it by the
+// time ctx is done, mistakes are only found once they break a rule.
+func NewGame(ctx context.Context, b *Board) *Game {
+	g := NewGameWithoutSolution(b)
+	res, err := Solve(ctx, b.Problem, Options{MakeGuesses: true})
+	if err == nil && res.Solved {
+		g.solution = res.Board
+	}
+	return g
+}
+
+// NewGameWithoutSolution is NewGame without solving the puzzle, for callers
+// that don't need mistakes pointed out before they break a rule.
+func NewGameWithoutSolution(b *Board) *Game {
+	g := &Game{b.Problem, NewGrid(b.Problem.Width, b.Problem.Height), EmptyCoordinateSet(), nil, nil, nil}
+	for r := range b.Grid {
+		copy(g.Grid[r], b.Grid[r])
+	}
+	for _, spec := range b.Problem.IslandSpecs {
+		g.clues.Add(Coordinate{spec.Row, spec.Col})
+	}
+	return g
+}
+
+func (g *Game) IsClue(c Coordinate) bool {
+	return g.clues.Contains(c)
+}
+
+func (g *Game) Get(c Coordinate) Cell {
+	return g.Grid[c.Row][c.Col]
+}
+
+// Set changes the cell at c, which must not hold a clue, and clears the redo
+// history.
+func (g *Game) Set(c Coordinate, cell Cell) bool {
+	if c.Row < 0 || c.Col < 0 || c.Row >= g.Problem.Height || c.Col >= g.Problem.Width || g.IsClue(c) || g.Get(c) == cell {
+		return false
+	}
+	g.undo = append(g.undo, Move{c, g.Get(c), cell})
+	g.redo = g.redo[:0]
+	g.Grid[c.Row][c.Col] = cell
+	return true
+}
+
+// Cycle changes the cell at c from UNKNOWN to PAINTED to CLEAR and back.
+func (g *Game) Cycle(c Coordinate) bool {
+	return g.Set(c, (g.Get(c)+1)%3)
+}
+
+func (g *Game) Undo() bool {
+	if len(g.undo) == 0 {
+		return false
+	}
+	m := g.undo[len(g.undo)-1]
+	g.undo = g.undo[:len(g.undo)-1]
+	g.redo = append(g.redo, m)
+	g.Grid[m.Coord.Row][m.Coord.Col] = m.From
+	return true
+}
+
+func (g *Game) Redo() bool {
+	if len(g.redo) == 0 {
+		return false
+	}
+	m := g.redo[len(g.redo)-1]
+	g.redo = g.redo[:len(g.redo)-1]
+	g.undo = append(g.undo, m)
+	g.Grid[m.Coord.Row][m.Coord.Col] = m.To
+	return true
+}
+
+// Board builds a Board from the game's grid. It fails if the grid paints a
+// clue.
+func (g *Game) Board() (*Board, error) {
+	b := BoardFromDef(g.Problem)
+	for r, row := range g.Grid {
+		for c, cell := range row {
+			if err := b.markInput(r, c, cell); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return b, nil
+}
+
+// Mistakes lists the marked cells that disagree with the solution, or nil if
+// the solution isn't known.
+func (g *Game) Mistakes() []Coordinate {
+	if g.solution == nil {
+		return nil
+	}
+	out := make([]Coordinate, 0)
+	for r, row := range g.Grid {
+		for c, cell := range row {
+			if cell != UNKNOWN && cell != g.solution.Grid[r][c] {
+				out = append(out, Coordinate{r, c})
+			}
+		}
+	}
+	return out
+}
+
+// Check returns an error describing the first mistake found on the board,
+// or nil if there is none.
+func (g *Game) Check() error {
+	if mistakes := g.Mistakes(); len(mistakes) > 0 {
+		return fmt.Errorf("%d cell(s) marked wrongly, starting with r%dc%d", len(mistakes), mistakes[0].Row, mistakes[0].Col)
+	}
+	return g.Violation()
+}
+
+// Violation checks the grid against the rules alone, without the solution:
+// it reports pools, oversized islands and islands that can no longer be
+// completed.
+func (g *Game) Violation() error {
+	b, err := g.Board()
+	if err != nil {
+		return err
+	}
+	s := NewSolver(b)
+	s.Progress = nil
+	s.PopulateIslandPossibilities()
+	return s.b.ContainsError()
+}
+
+func (g *Game) IsSolved() (bool, error) {
+	b, err := g.Board()
+	if err != nil {
+		return false, err
+	}
+	return b.IsSolved()
+}
+
+// Hint returns the next cell the solver would mark from the current grid,
+// without marking it. It fails if the grid contains a mistake or ctx is
+// cancelled first.
+func (g *Game) Hint(ctx context.Context) (*Deduction, error) {
+	if err := g.Check(); err != nil {
+		return nil, err
+	}
+	b, err := g.Board()
+	if err != nil {
+		return nil, err
+	}
+	s := NewSolverWithContext(ctx, b)
+	s.Progress = nil
+	for {
+		d := s.NextDeduction()
+		if d == nil {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("no hint available")
+		}
+		if !d.IsPruning() {
+			return d, nil
+		}
+	}
+}
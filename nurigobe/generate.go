@@ -0,0 +1,283 @@
+package nurigobe
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// GeneratorOptions controls the puzzles produced by Generate.
+type GeneratorOptions struct {
+	Width  int
+	Height int
+	Seed   int64
+	// MaxIslandSize caps the size of each island (and therefore each clue).
+	// A puzzle with a clue above MaxCharClue prints as a token grid.
+	MaxIslandSize int
+	// Layouts is the number of wall layouts to try before giving up.
+	Layouts int
+	// Rearrangements is the number of clue placements tried per layout.
+	Rearrangements int
+	// SolveSteps bounds the passes through the rules and the guesses the
+	// solver may make on each candidate; candidates it cannot finish within
+	// them are rejected.
+	SolveSteps int
+}
+
+func DefaultGeneratorOptions(w int, h int, seed int64) GeneratorOptions {
+	return GeneratorOptions{w, h, seed, 9, 100, 5, 100}
+}
+
+// Generate returns a w x h puzzle that the solver can solve without help and
+// that therefore has a unique solution. The same seed produces the same
+// puzzle on any machine.
+func Generate(w int, h int, seed int64) (ProblemDef, error) {
+	return GenerateContext(context.Background(), DefaultGeneratorOptions(w, h, seed))
+}
+
+// GenerateContext builds random wall layouts (one connected wall, no 2x2
+// pools), puts one clue on each island and then moves clues around until the
+// solver reaches a unique solution. Finally, it removes whole islands as long
+// as the puzzle stays uniquely solvable.
+func GenerateContext(ctx context.Context, opts GeneratorOptions) (ProblemDef, error) {
+	if opts.Width < 2 || opts.Height < 2 {
+		return ProblemDef{}, fmt.Errorf("puzzle must be at least 2x2 (got %dx%d)", opts.Width, opts.Height)
+	}
+	if opts.MaxIslandSize < 1 {
+		return ProblemDef{}, fmt.Errorf("maximum island size %d must be at least 1", opts.MaxIslandSize)
+	}
+	g := generator{opts, rand.New(rand.NewSource(opts.Seed)), nil}
+	for layout := 0; layout < opts.Layouts; layout++ {
+		if err := ctx.Err(); err != nil {
+			return ProblemDef{}, err
+		}
+		if !g.buildLayout() {
+			continue
+		}
+		islands := g.islands()
+		for attempt := 0; attempt < opts.Rearrangements; attempt++ {
+			def := g.placeClues(islands)
+			solved, err := g.isSolvable(ctx, def)
+			if err != nil {
+				return ProblemDef{}, err
+			}
+			if solved {
+				return g.removeIslands(ctx, islands, def)
+			}
+		}
+	}
+	return ProblemDef{}, fmt.Errorf("no uniquely solvable puzzle found after %d layouts", opts.Layouts)
+}
+
+// isSolvable reports whether the solver can solve def completely within the
+// generator's SolveSteps, which proves that the solution is unique. It tests
+// one guess at a time, so that whether a candidate is solved doesn't depend
+// on which parallel guess finishes first.
+func (g *generator) isSolvable(ctx context.Context, def ProblemDef) (bool, error) {
+	res, err := Solve(ctx, def, Options{MakeGuesses: true, MaxSteps: g.opts.SolveSteps})
+	if err != nil {
+		return false, err
+	}
+	return res.Solved, nil
+}
+
+type generator struct {
+	opts GeneratorOptions
+	rng  *rand.Rand
+	grid [][]Cell
+}
+
+func (g *generator) inBounds(c Coordinate) bool {
+	return c.Row >= 0 && c.Col >= 0 && c.Row < g.opts.Height && c.Col < g.opts.Width
+}
+
+func (g *generator) neighbors(c Coordinate) []Coordinate {
+	out := make([]Coordinate, 0, 4)
+	for _, n := range []Coordinate{c.Translate(-1, 0), c.Translate(1, 0), c.Translate(0, -1), c.Translate(0, 1)} {
+		if g.inBounds(n) {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// region returns the cells of the given colour connected to start.
+func (g *generator) region(start Coordinate, color Cell) *CoordinateSet {
+	cs := EmptyCoordinateSet()
+	if g.grid[start.Row][start.Col] != color {
+		return cs
+	}
+	queue := []Coordinate{start}
+	cs.Add(start)
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+		for _, n := range g.neighbors(c) {
+			if g.grid[n.Row][n.Col] == color && !cs.Contains(n) {
+				cs.Add(n)
+				queue = append(queue, n)
+			}
+		}
+	}
+	return cs
+}
+
+func (g *generator) wallsConnected() bool {
+	walls := 0
+	start := NilCoordinate()
+	for r := 0; r < g.opts.Height; r++ {
+		for c := 0; c < g.opts.Width; c++ {
+			if g.grid[r][c] == PAINTED {
+				walls++
+				start = Coordinate{r, c}
+			}
+		}
+	}
+	if walls == 0 {
+		return false
+	}
+	return g.region(start, PAINTED).Size() == walls
+}
+
+func (g *generator) isPool(r int, c int) bool {
+	if r < 0 || c < 0 || r+1 >= g.opts.Height || c+1 >= g.opts.Width {
+		return false
+	}
+	return g.grid[r][c] == PAINTED && g.grid[r+1][c] == PAINTED && g.grid[r][c+1] == PAINTED && g.grid[r+1][c+1] == PAINTED
+}
+
+func (g *generator) pools() []Coordinate {
+	out := make([]Coordinate, 0)
+	for r := 0; r < g.opts.Height-1; r++ {
+		for c := 0; c < g.opts.Width-1; c++ {
+			if g.isPool(r, c) {
+				out = append(out, Coordinate{r, c})
+			}
+		}
+	}
+	return out
+}
+
+// tryClear clears c if doing so keeps the walls connected and the island it
+// joins no bigger than MaxIslandSize.
+func (g *generator) tryClear(c Coordinate) bool {
+	if g.grid[c.Row][c.Col] != PAINTED {
+		return false
+	}
+	g.grid[c.Row][c.Col] = CLEAR
+	if g.region(c, CLEAR).Size() <= g.opts.MaxIslandSize && g.wallsConnected() {
+		return true
+	}
+	g.grid[c.Row][c.Col] = PAINTED
+	return false
+}
+
+// buildLayout starts from an all-wall grid and clears cells until there are
+// no pools left, then clears a few more cells at random for variety.
+func (g *generator) buildLayout() bool {
+	g.grid = NewGrid(g.opts.Width, g.opts.Height)
+	for r := range g.grid {
+		for c := range g.grid[r] {
+			g.grid[r][c] = PAINTED
+		}
+	}
+	for {
+		pools := g.pools()
+		if len(pools) == 0 {
+			break
+		}
+		p := pools[g.rng.Intn(len(pools))]
+		cleared := false
+		for _, idx := range g.rng.Perm(4) {
+			if g.tryClear(p.Translate(idx/2, idx%2)) {
+				cleared = true
+				break
+			}
+		}
+		if !cleared {
+			return false
+		}
+	}
+	extra := g.opts.Width * g.opts.Height / 10
+	for i := 0; i < extra; i++ {
+		g.tryClear(Coordinate{g.rng.Intn(g.opts.Height), g.rng.Intn(g.opts.Width)})
+	}
+	return true
+}
+
+// islands lists the clear regions of the current layout in row-major order
+// of their first cell.
+func (g *generator) islands() []*CoordinateSet {
+	seen := EmptyCoordinateSet()
+	out := make([]*CoordinateSet, 0)
+	for r := 0; r < g.opts.Height; r++ {
+		for c := 0; c < g.opts.Width; c++ {
+			if g.grid[r][c] != CLEAR || seen.Contains(Coordinate{r, c}) {
+				continue
+			}
+			island := g.region(Coordinate{r, c}, CLEAR)
+			seen.AddAll(island)
+			out = append(out, island)
+		}
+	}
+	return out
+}
+
+// placeClues puts one clue on a random cell of each island.
+func (g *generator) placeClues(islands []*CoordinateSet) ProblemDef {
+	specs := make([]IslandSpec, 0, len(islands))
+	for _, island := range islands {
+		members := island.ToSlice()
+		c := members[g.rng.Intn(len(members))]
+		specs = append(specs, IslandSpec{c.Col, c.Row, island.Size()})
+	}
+	return g.def(specs)
+}
+
+// def builds a ProblemDef with its specs in the row-major order that
+// DefFromString produces, so that the puzzle round-trips through String.
+func (g *generator) def(specs []IslandSpec) ProblemDef {
+	sorted := make([]IslandSpec, len(specs))
+	copy(sorted, specs)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Row < sorted[j].Row || (sorted[i].Row == sorted[j].Row && sorted[i].Col < sorted[j].Col)
+	})
+	def := ProblemDef{g.opts.Width, g.opts.Height, g.opts.Width * g.opts.Height, sorted, 0}
+	for _, spec := range sorted {
+		def.TargetWallCount += spec.Size
+	}
+	return def
+}
+
+// removeIslands tries turning each island into wall in turn, keeping the
+// change whenever the layout stays valid and the solver still solves it.
+func (g *generator) removeIslands(ctx context.Context, islands []*CoordinateSet, def ProblemDef) (ProblemDef, error) {
+	for _, idx := range g.rng.Perm(len(islands)) {
+		island := islands[idx]
+		for _, c := range island.ToSlice() {
+			g.grid[c.Row][c.Col] = PAINTED
+		}
+		if len(g.pools()) == 0 && g.wallsConnected() {
+			specs := make([]IslandSpec, 0, len(def.IslandSpecs))
+			for _, spec := range def.IslandSpecs {
+				if !island.Contains(Coordinate{spec.Row, spec.Col}) {
+					specs = append(specs, spec)
+				}
+			}
+			candidate := g.def(specs)
+			solved, err := g.isSolvable(ctx, candidate)
+			if err != nil {
+				return ProblemDef{}, err
+			}
+			if solved {
+				def = candidate
+				continue
+			}
+		}
+		for _, c := range island.ToSlice() {
+			g.grid[c.Row][c.Col] = CLEAR
+		}
+	}
+	return def, nil
+}
@@ -0,0 +1,37 @@
+package nurigobe
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGenerateReproducible(t *testing.T) {
+	for seed := int64(1); seed <= 3; seed++ {
+		first, err := Generate(6, 6, seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := Generate(6, 6, seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if first.String() != second.String() {
+			t.Fatalf("seed %d gave two different puzzles:\n%v\n%v", seed, first, second)
+		}
+		if sc := CountSolutions(first, 1); !sc.IsUnique() {
+			t.Fatalf("seed %d: got %v for\n%v", seed, sc, first)
+		}
+	}
+}
+
+func TestGenerateLargeIslands(t *testing.T) {
+	opts := DefaultGeneratorOptions(6, 6, 1)
+	opts.MaxIslandSize = 100
+	def, err := GenerateContext(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sc := CountSolutions(def, 1); !sc.IsUnique() {
+		t.Fatalf("got %v for\n%v", sc, def)
+	}
+}
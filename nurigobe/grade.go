@@ -0,0 +1,165 @@
+package nurigobe
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Technique groups rules by how hard they are for a human to apply.
+type Technique int
+
+const (
+	// TechniqueLocal rules look only at a cell's immediate surroundings:
+	// two-bordered cells, elbows, one-liberty extensions and island borders.
+	TechniqueLocal Technique = iota
+	// TechniquePossibility rules reason about the shapes an island could
+	// still take.
+	TechniquePossibility
+	// TechniqueShallowGuess is trial and error followed by the cheap rules.
+	TechniqueShallowGuess
+	// TechniqueDeepGuess is trial and error followed by every rule.
+	TechniqueDeepGuess
+)
+
+var techniqueLabels = []string{"easy", "medium", "hard", "expert"}
+
+// techniqueWeights is how many points each deduction of a technique adds to
+// a puzzle's score.
+var techniqueWeights = []int{1, 3, 10, 25}
+
+func (t Technique) Label() string {
+	return techniqueLabels[t]
+}
+
+func RuleTechnique(r Rule) Technique {
+	switch r {
+	case RulePaintTwoBorderedCells, RuleExtendIslandsOneLiberty, RuleExtendWallIslandsOneLiberty, RuleAddIslandBorders, RuleFillElbows:
+		return TechniqueLocal
+	case RuleShallowGuess:
+		return TechniqueShallowGuess
+	case RuleDeepGuess:
+		return TechniqueDeepGuess
+	}
+	return TechniquePossibility
+}
+
+// Grade is the difficulty of a puzzle as measured by the rules the solver
+// needed to solve it.
+type Grade struct {
+	// Hardest is the hardest technique that had to be used to mark a cell.
+	// Pruning possibilities doesn't count on its own, since only the
+	// possibility-based rules and guesses can take advantage of it.
+	Hardest Technique
+	// Label is "easy", "medium", "hard" or "expert", following Hardest, or
+	// "unsolved" if the solver could not finish the puzzle.
+	Label string
+	// Score is the sum of the technique weights of every marked cell.
+	Score int
+	// Counts is the number of deductions made by each rule. A rule that only
+	// pruned possibilities counts once per time it was applied.
+	Counts map[Rule]int
+	Solved bool
+	Reason error
+}
+
+func (g *Grade) String() string {
+	rules := make([]string, 0, len(g.Counts))
+	for r := range g.Counts {
+		rules = append(rules, string(r))
+	}
+	sort.Strings(rules)
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s (score %d)\n", g.Label, g.Score))
+	for _, r := range rules {
+		sb.WriteString(fmt.Sprintf("  %-30s %d\n", r, g.Counts[Rule(r)]))
+	}
+	if !g.Solved {
+		sb.WriteString(fmt.Sprintf("Not solved (%v)\n", g.Reason))
+	}
+	return sb.String()
+}
+
+// GradePuzzle solves def and grades it.
+func GradePuzzle(ctx context.Context, def ProblemDef) (*Grade, error) {
+	return GradeBoard(ctx, BoardFromDef(def))
+}
+
+// GradeBoard solves b, which is modified in place, and grades it. Unlike
+// AutoSolve, the grader always falls back to the easiest technique that
+// makes progress, so a deduction is only credited to a harder technique when
+// none of the easier ones could have made it.
+func GradeBoard(ctx context.Context, b *Board) (*Grade, error) {
+	s := NewSolverWithContext(ctx, b)
+	s.Progress = nil
+	s.Trace = NewTrace()
+	g := &Grade{TechniqueLocal, "", 0, make(map[Rule]int), false, nil}
+	s.InitSolve()
+	tiers := [][]func() bool{
+		{
+			s.PaintTwoBorderedCells,
+			s.ExtendIslandsOneLiberty,
+			s.AddIslandBorders,
+			s.FillElbows,
+			s.ExtendWallIslandsOneLiberty,
+		},
+		{
+			s.StripAllPossibilities,
+			s.PaintUnreachables,
+			s.ConnectUnrootedIslands,
+			s.FindSinglePoolPreventers,
+			s.FillIslandNecessaries,
+			s.ExtendWallIslands,
+			s.EliminateIntolerables,
+			s.EliminateWallSplitters,
+		},
+		{
+			func() bool { return s.MakeAGuess(true, true) },
+			func() bool { return s.MakeAGuess(false, true) },
+		},
+		{
+			func() bool { return s.MakeAGuess(true, false) },
+			func() bool { return s.MakeAGuess(false, false) },
+		},
+	}
+	marked := len(s.Trace.Steps)
+	changed := true
+	for changed && !s.Cancelled() {
+		changed = false
+		if s.b.TotalMarked == s.b.Problem.Size || s.b.ContainsError() != nil {
+			break
+		}
+		//the tiers cover every rule, so nothing is propagated
+		s.b.discardPropagation()
+	oneTier:
+		for tier, rules := range tiers {
+			for _, r := range rules {
+				if !r() {
+					continue
+				}
+				changed = true
+				if len(s.Trace.Steps) == marked {
+					g.Counts[s.rule]++
+				} else if Technique(tier) > g.Hardest {
+					g.Hardest = Technique(tier)
+				}
+				marked = len(s.Trace.Steps)
+				break oneTier
+			}
+		}
+	}
+	for _, d := range s.Trace.Steps {
+		g.Counts[d.Rule]++
+		g.Score += techniqueWeights[RuleTechnique(d.Rule)]
+	}
+	g.Solved, g.Reason = b.IsSolved()
+	g.Label = g.Hardest.Label()
+	if !g.Solved {
+		g.Label = "unsolved"
+	}
+	if err := ctx.Err(); err != nil {
+		return g, err
+	}
+	return g, nil
+}
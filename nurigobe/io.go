@@ -3,9 +3,15 @@ package nurigobe
 import (
 	"fmt"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 )
 
+// MaxCharClue is the largest clue the single-character grid format can
+// express; the letters after W are taken by X for walls.
+const MaxCharClue = 58
+
 func islandSpecChar(sz int) string {
 	if sz < 10 {
 		return string(rune(sz + '0'))
@@ -13,7 +19,7 @@ func islandSpecChar(sz int) string {
 	if sz < 36 {
 		return string(rune((sz - 10) + 'a'))
 	}
-	if sz < 62 {
+	if sz <= MaxCharClue {
 		return string(rune((sz - 36) + 'A'))
 	}
 	return "?"
@@ -41,6 +47,9 @@ func DefFromString(input string) (ProblemDef, error) {
 			lines = append(lines, txt)
 		}
 	}
+	if len(lines) == 0 {
+		return ProblemDef{}, fmt.Errorf("problem definition is empty")
+	}
 	prob.Width = len(lines[0])
 	prob.Height = len(lines)
 	prob.Size = prob.Width * prob.Height
@@ -64,13 +73,7 @@ func BoardFromString(input string) (*Board, error) {
     if err != nil {
         return nil, err
     }
-	b := Board{def, NewGrid(def.Width, def.Height), NewGrid(def.Width, def.Height), make([]*Island, 0), make([]*Island, 0), make([]*CoordinateSet, 0), 0}
-	for _, spec := range b.Problem.IslandSpecs {
-		b.Grid[spec.Row][spec.Col] = CLEAR
-		b.TotalMarked++
-		b.Islands = append(b.Islands, MakeRootedIsland(spec.Row, spec.Col, spec.Size))
-		b.DiagonalSets = append(b.DiagonalSets, SingleCoordinateSet(Coordinate{spec.Row, spec.Col}))
-	}
+	b := BoardFromDef(def)
 	lines := make([]string, 0)
 	for _, txt := range strings.Split(input, "\n") {
 		txt = strings.Trim(txt, "\r\n")
@@ -87,7 +90,7 @@ func BoardFromString(input string) (*Board, error) {
 			}
 		}
 	}
-	return &b, nil
+	return b, nil
 }
 
 func GetBoardFromFile(f string) (*Board, error) {
@@ -95,5 +98,388 @@ func GetBoardFromFile(f string) (*Board, error) {
 	if err != nil {
 		return nil, err
 	}
-	return BoardFromString(string(data))
+	return ParseBoard(string(data))
+}
+
+// ParseBoard reads a board in any of the supported text formats: JSON
+// puzzles, janko.at files, token grids and single-character grids.
+func ParseBoard(input string) (*Board, error) {
+	if IsJSON(input) {
+		p, err := PuzzleFromJSON(input)
+		if err != nil {
+			return nil, err
+		}
+		return p.Board(), nil
+	}
+	if IsJanko(input) {
+		j, err := JankoFromString(input)
+		if err != nil {
+			return nil, err
+		}
+		return BoardFromDef(j.Def), nil
+	}
+	if IsTokenGrid(input) {
+		return BoardFromTokens(input)
+	}
+	if !evenCharRows(input) {
+		if tb, err := BoardFromTokens(input); err == nil {
+			return tb, nil
+		}
+		if _, some := spacedCells(tokenRows(input)); some {
+			return nil, fmt.Errorf("problem definition is ambiguous: some rows have cells separated by spaces and others don't")
+		}
+	}
+	return BoardFromString(input)
+}
+
+// evenCharRows reports whether input's non-empty lines all have the same
+// length, as the rows of a single-character grid do. Spaces are empty cells
+// in that format, so a spaced-out row only makes the input ambiguous when
+// the rows can't be read as characters.
+func evenCharRows(input string) bool {
+	width := -1
+	for _, txt := range strings.Split(input, "\n") {
+		txt = strings.Trim(txt, "\r\n")
+		if len(txt) == 0 {
+			continue
+		}
+		if width >= 0 && len(txt) != width {
+			return false
+		}
+		width = len(txt)
+	}
+	return width > 0
+}
+
+func isTokenSeparator(r rune) bool {
+	return r == ',' || r == ' ' || r == '\t' || r == '\r'
+}
+
+func tokenRows(input string) [][]string {
+	rows := make([][]string, 0)
+	for _, txt := range strings.Split(input, "\n") {
+		fields := strings.FieldsFunc(txt, isTokenSeparator)
+		if len(fields) > 0 {
+			rows = append(rows, fields)
+		}
+	}
+	return rows
+}
+
+// IsTokenGrid reports whether input is a token grid: cells separated by
+// commas or whitespace, with clues written as integers. Since the
+// single-character format allows spaces for empty cells, a grid without
+// commas only counts as tokens if it has a space-separated clue of two or
+// more digits or if every row splits into the same number of one-character
+// cells, at least two.
+func IsTokenGrid(input string) bool {
+	if strings.Contains(input, ",") {
+		return true
+	}
+	rows := tokenRows(input)
+	for _, row := range rows {
+		if len(row) < 2 {
+			continue
+		}
+		for _, tok := range row {
+			if n, err := strconv.Atoi(tok); err == nil && n >= 10 {
+				return true
+			}
+		}
+	}
+	all, _ := spacedCells(rows)
+	return all
+}
+
+// spacedCells reports whether every row splits into the same number of
+// one-character cells, at least two, and whether any row splits into two or
+// more one-character cells at all.
+func spacedCells(rows [][]string) (bool, bool) {
+	all, some := len(rows) > 0, false
+	for _, row := range rows {
+		spaced := len(row) >= 2
+		for _, tok := range row {
+			spaced = spaced && len(tok) == 1
+		}
+		some = some || spaced
+		all = all && spaced && len(row) == len(rows[0])
+	}
+	return all, some
+}
+
+// DefFromTokens parses a token grid: integers are clues, "_" is an unknown
+// cell, "." a clear cell and "X" a wall.
+func DefFromTokens(input string) (ProblemDef, error) {
+	rows := tokenRows(input)
+	if len(rows) == 0 {
+		return ProblemDef{}, fmt.Errorf("problem definition is empty")
+	}
+	prob := ProblemDef{len(rows[0]), len(rows), len(rows[0]) * len(rows), nil, 0}
+	for ri, row := range rows {
+		if len(row) != prob.Width {
+			return ProblemDef{}, fmt.Errorf("problem definition row %d has %d cells (expected %d)", ri+1, len(row), prob.Width)
+		}
+		for ci, tok := range row {
+			switch tok {
+			case "_", ".", "X", "x":
+				continue
+			}
+			count, err := strconv.Atoi(tok)
+			if err != nil || count < 1 {
+				return ProblemDef{}, fmt.Errorf("problem definition has invalid cell %q at %v", tok, Coordinate{ri, ci})
+			}
+			prob.IslandSpecs = append(prob.IslandSpecs, IslandSpec{ci, ri, count})
+			prob.TargetWallCount += count
+		}
+	}
+	return prob, nil
+}
+
+func BoardFromTokens(input string) (*Board, error) {
+	def, err := DefFromTokens(input)
+	if err != nil {
+		return nil, err
+	}
+	b := BoardFromDef(def)
+	for ri, row := range tokenRows(input) {
+		for ci, tok := range row {
+			var err error
+			if tok == "X" || tok == "x" {
+				err = b.markInput(ri, ci, PAINTED)
+			} else if tok == "." {
+				err = b.markInput(ri, ci, CLEAR)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return b, nil
+}
+
+// JankoPuzzle is a puzzle in the format used by janko.at: a [setup] section
+// of "key = value" lines, a [problem] grid of space-separated cells with "-"
+// for blanks and, optionally, a [solution] grid with "x" for walls.
+type JankoPuzzle struct {
+	Def ProblemDef
+	// Setup holds the [setup] entries other than the dimensions, e.g.
+	// author, source and info.
+	Setup map[string]string
+	// Solution is the published solution, or nil if there is none.
+	Solution *Board
+}
+
+// IsJanko reports whether input looks like a janko.at puzzle file.
+func IsJanko(input string) bool {
+	return strings.Contains(input, "[problem]")
+}
+
+func jankoSections(input string) map[string][]string {
+	sections := make(map[string][]string)
+	current := ""
+	for _, txt := range strings.Split(input, "\n") {
+		txt = strings.TrimSpace(txt)
+		if len(txt) == 0 {
+			continue
+		}
+		if strings.HasPrefix(txt, "[") && strings.HasSuffix(txt, "]") {
+			current = strings.ToLower(txt[1 : len(txt)-1])
+			if _, ok := sections[current]; !ok {
+				sections[current] = make([]string, 0)
+			}
+			continue
+		}
+		sections[current] = append(sections[current], txt)
+	}
+	return sections
+}
+
+func jankoGrid(lines []string, w int, h int, section string) ([][]string, error) {
+	if len(lines) != h {
+		return nil, fmt.Errorf("[%s] has %d rows (expected %d)", section, len(lines), h)
+	}
+	grid := make([][]string, 0, h)
+	for ri, line := range lines {
+		row := strings.Fields(line)
+		if len(row) != w {
+			return nil, fmt.Errorf("[%s] row %d has %d cells (expected %d)", section, ri+1, len(row), w)
+		}
+		grid = append(grid, row)
+	}
+	return grid, nil
+}
+
+// JankoFromString parses a janko.at puzzle file.
+func JankoFromString(input string) (*JankoPuzzle, error) {
+	sections := jankoSections(input)
+	j := JankoPuzzle{ProblemDef{}, make(map[string]string), nil}
+	for _, line := range sections["setup"] {
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		j.Setup[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	if puzzle, ok := j.Setup["puzzle"]; ok && strings.ToLower(puzzle) != "nurikabe" {
+		return nil, fmt.Errorf("janko puzzle is a %s, not a nurikabe", puzzle)
+	}
+	problem, ok := sections["problem"]
+	if !ok || len(problem) == 0 {
+		return nil, fmt.Errorf("janko puzzle has no [problem] section")
+	}
+	w, h := len(strings.Fields(problem[0])), len(problem)
+	for _, dim := range []struct {
+		key string
+		val *int
+	}{{"size", &w}, {"size", &h}, {"cols", &w}, {"rows", &h}} {
+		if v, ok := j.Setup[dim.key]; ok {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("janko puzzle has invalid %s %q", dim.key, v)
+			}
+			*dim.val = n
+		}
+	}
+	for _, key := range []string{"size", "rows", "cols"} {
+		delete(j.Setup, key)
+	}
+	grid, err := jankoGrid(problem, w, h, "problem")
+	if err != nil {
+		return nil, err
+	}
+	j.Def = ProblemDef{w, h, w * h, nil, 0}
+	for ri, row := range grid {
+		for ci, tok := range row {
+			if tok == "-" || tok == "." {
+				continue
+			}
+			count, err := strconv.Atoi(tok)
+			if err != nil || count < 1 {
+				return nil, fmt.Errorf("[problem] has invalid cell %q at %v", tok, Coordinate{ri, ci})
+			}
+			j.Def.IslandSpecs = append(j.Def.IslandSpecs, IslandSpec{ci, ri, count})
+			j.Def.TargetWallCount += count
+		}
+	}
+	solution, ok := sections["solution"]
+	if !ok {
+		return &j, nil
+	}
+	grid, err = jankoGrid(solution, w, h, "solution")
+	if err != nil {
+		return nil, err
+	}
+	j.Solution = BoardFromDef(j.Def)
+	for ri, row := range grid {
+		for ci, tok := range row {
+			var err error
+			switch tok {
+			case "x", "X", "#":
+				err = j.Solution.markInput(ri, ci, PAINTED)
+			case "-", ".":
+				err = j.Solution.markInput(ri, ci, CLEAR)
+			default:
+				if _, err := strconv.Atoi(tok); err != nil {
+					return nil, fmt.Errorf("[solution] has invalid cell %q at %v", tok, Coordinate{ri, ci})
+				}
+			}
+			if err != nil {
+				return nil, fmt.Errorf("[solution]: %v", err)
+			}
+		}
+	}
+	return &j, nil
+}
+
+// JankoFromBoard describes b as a janko.at puzzle, including its grid as the
+// solution if every cell is marked.
+func JankoFromBoard(b *Board) *JankoPuzzle {
+	j := JankoPuzzle{b.Problem, map[string]string{"puzzle": "nurikabe"}, nil}
+	if b.TotalMarked == b.Problem.Size {
+		j.Solution = b
+	}
+	return &j
+}
+
+func (j *JankoPuzzle) String() string {
+	clues := make(map[Coordinate]int, len(j.Def.IslandSpecs))
+	for _, spec := range j.Def.IslandSpecs {
+		clues[Coordinate{spec.Row, spec.Col}] = spec.Size
+	}
+	var sb strings.Builder
+	sb.WriteString("[setup]\n")
+	puzzle, ok := j.Setup["puzzle"]
+	if !ok {
+		puzzle = "nurikabe"
+	}
+	sb.WriteString(fmt.Sprintf("puzzle = %s\n", puzzle))
+	if j.Def.Width == j.Def.Height {
+		sb.WriteString(fmt.Sprintf("size = %d\n", j.Def.Width))
+	} else {
+		sb.WriteString(fmt.Sprintf("rows = %d\ncols = %d\n", j.Def.Height, j.Def.Width))
+	}
+	keys := make([]string, 0, len(j.Setup))
+	for k := range j.Setup {
+		if k != "puzzle" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		sb.WriteString(fmt.Sprintf("%s = %s\n", k, j.Setup[k]))
+	}
+	writeGrid := func(section string, cell func(r int, c int) string) {
+		sb.WriteString(fmt.Sprintf("[%s]\n", section))
+		for r := 0; r < j.Def.Height; r++ {
+			row := make([]string, 0, j.Def.Width)
+			for c := 0; c < j.Def.Width; c++ {
+				if sz, ok := clues[Coordinate{r, c}]; ok {
+					row = append(row, strconv.Itoa(sz))
+				} else {
+					row = append(row, cell(r, c))
+				}
+			}
+			sb.WriteString(strings.Join(row, " ") + "\n")
+		}
+	}
+	writeGrid("problem", func(r int, c int) string { return "-" })
+	if j.Solution != nil {
+		writeGrid("solution", func(r int, c int) string {
+			if j.Solution.Grid[r][c] == PAINTED {
+				return "x"
+			}
+			return "-"
+		})
+	}
+	sb.WriteString("[end]\n")
+	return sb.String()
+}
+
+// Check compares b against the published solution.
+func (j *JankoPuzzle) Check(b *Board) error {
+	if j.Solution == nil {
+		return fmt.Errorf("puzzle has no published solution")
+	}
+	return Check(b, j.Solution)
+}
+
+func GetJankoFromFile(f string) (*JankoPuzzle, error) {
+	data, err := os.ReadFile(f)
+	if err != nil {
+		return nil, err
+	}
+	return JankoFromString(string(data))
+}
+
+// GetBoard loads a board from a puzz.link URL or, failing that, from a
+// problem file.
+func GetBoard(src string) (*Board, error) {
+	if IsPuzzLinkURL(src) {
+		def, err := DefFromPuzzLink(src)
+		if err != nil {
+			return nil, err
+		}
+		return BoardFromDef(def), nil
+	}
+	return GetBoardFromFile(src)
 }
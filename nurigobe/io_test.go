@@ -0,0 +1,78 @@
+package nurigobe
+
+import "testing"
+
+func TestParseBoardSpacedSingleDigits(t *testing.T) {
+	b, err := ParseBoard("2 _ _\n_ _ _\n_ _ 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Problem.Width != 3 || b.Problem.Height != 3 {
+		t.Fatalf("got a %dx%d board, want 3x3", b.Problem.Width, b.Problem.Height)
+	}
+	want := []IslandSpec{{0, 0, 2}, {2, 2, 1}}
+	if len(b.Problem.IslandSpecs) != len(want) {
+		t.Fatalf("got clues %v, want %v", b.Problem.IslandSpecs, want)
+	}
+	for i, spec := range want {
+		if b.Problem.IslandSpecs[i] != spec {
+			t.Fatalf("got clues %v, want %v", b.Problem.IslandSpecs, want)
+		}
+	}
+}
+
+func TestParseBoardAmbiguousSpacing(t *testing.T) {
+	if b, err := ParseBoard("2 _ _\n__1"); err == nil {
+		t.Fatalf("expected an error, got\n%v", b)
+	}
+}
+
+func TestParseBoardSingleCharacters(t *testing.T) {
+	b, err := ParseBoard("2__\n___\n__1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Problem.Width != 3 || len(b.Problem.IslandSpecs) != 2 {
+		t.Fatalf("got %v", b)
+	}
+}
+
+// TestParseBoardReadmeExample parses the README's p3.txt, a single-character
+// grid whose spaces are empty cells and which has two clues in one row.
+func TestParseBoardReadmeExample(t *testing.T) {
+	input := "  7       \n" +
+		"     7    \n" +
+		"          \n" +
+		"          \n" +
+		"     2 5  \n" +
+		"    4    6\n" +
+		"      8   \n" +
+		"    7     \n" +
+		"          \n" +
+		"          \n" +
+		"   8      \n" +
+		"2   2    3\n" +
+		" 3        \n" +
+		"    4     \n"
+	b, err := ParseBoard(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Problem.Width != 10 || b.Problem.Height != 14 || len(b.Problem.IslandSpecs) != 14 {
+		t.Fatalf("got a %dx%d board with %d clues, want 10x14 with 14", b.Problem.Width, b.Problem.Height, len(b.Problem.IslandSpecs))
+	}
+	if spec := b.Problem.IslandSpecs[3]; spec != (IslandSpec{7, 4, 5}) {
+		t.Fatalf("got clue %v, want {7 4 5}", spec)
+	}
+}
+
+func TestJankoSolutionWallOnClue(t *testing.T) {
+	input := "[problem]\n2 - -\n- - 1\n[solution]\nx - x\nx x 1\n"
+	if j, err := JankoFromString(input); err == nil {
+		t.Fatalf("expected an error, got solution\n%v", j.Solution)
+	}
+	input = "[problem]\n2 - -\n- - 1\n[solution]\n2 - x\nx x 1\n"
+	if _, err := JankoFromString(input); err != nil {
+		t.Fatal(err)
+	}
+}
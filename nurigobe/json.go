@@ -0,0 +1,278 @@
+package nurigobe
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type coordJSON struct {
+	Row int `json:"row"`
+	Col int `json:"col"`
+}
+
+type clueJSON struct {
+	Row  int `json:"row"`
+	Col  int `json:"col"`
+	Size int `json:"size"`
+}
+
+type problemJSON struct {
+	Width  int        `json:"width"`
+	Height int        `json:"height"`
+	Clues  []clueJSON `json:"clues"`
+}
+
+func (p ProblemDef) toJSON() problemJSON {
+	pj := problemJSON{p.Width, p.Height, make([]clueJSON, 0, len(p.IslandSpecs))}
+	for _, spec := range p.IslandSpecs {
+		pj.Clues = append(pj.Clues, clueJSON{spec.Row, spec.Col, spec.Size})
+	}
+	return pj
+}
+
+func (pj problemJSON) toDef() (ProblemDef, error) {
+	if pj.Width < 1 || pj.Height < 1 {
+		return ProblemDef{}, fmt.Errorf("problem has invalid dimensions %dx%d", pj.Width, pj.Height)
+	}
+	p := ProblemDef{pj.Width, pj.Height, pj.Width * pj.Height, make([]IslandSpec, 0, len(pj.Clues)), 0}
+	seen := EmptyCoordinateSetSz(pj.Height)
+	for _, clue := range pj.Clues {
+		c := Coordinate{clue.Row, clue.Col}
+		if clue.Row < 0 || clue.Col < 0 || clue.Row >= p.Height || clue.Col >= p.Width {
+			return ProblemDef{}, fmt.Errorf("clue at %v is out of bounds", c)
+		}
+		if seen.Contains(c) {
+			return ProblemDef{}, fmt.Errorf("problem has more than one clue at %v", c)
+		}
+		seen.Add(c)
+		if clue.Size < 1 {
+			return ProblemDef{}, fmt.Errorf("clue at %v has invalid size %d", c, clue.Size)
+		}
+		p.IslandSpecs = append(p.IslandSpecs, IslandSpec{clue.Col, clue.Row, clue.Size})
+		p.TargetWallCount += clue.Size
+	}
+	return p, nil
+}
+
+// MarshalJSON writes the problem as its dimensions and a list of clues.
+func (p ProblemDef) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.toJSON())
+}
+
+func (p *ProblemDef) UnmarshalJSON(data []byte) error {
+	var pj problemJSON
+	if err := json.Unmarshal(data, &pj); err != nil {
+		return err
+	}
+	def, err := pj.toDef()
+	if err != nil {
+		return err
+	}
+	*p = def
+	return nil
+}
+
+// gridRows writes the grid as one string per row, with "_" for unknown
+// cells, "X" for walls and "." for clear cells, including numbered ones.
+func gridRows(grid [][]Cell) []string {
+	rows := make([]string, 0, len(grid))
+	for _, row := range grid {
+		var sb strings.Builder
+		for _, cell := range row {
+			switch cell {
+			case PAINTED:
+				sb.WriteByte('X')
+			case CLEAR:
+				sb.WriteByte('.')
+			default:
+				sb.WriteByte('_')
+			}
+		}
+		rows = append(rows, sb.String())
+	}
+	return rows
+}
+
+// applyGridRows marks b's cells as described by rows from gridRows.
+func (b *Board) applyGridRows(rows []string) error {
+	if len(rows) != b.Problem.Height {
+		return fmt.Errorf("grid has %d rows (expected %d)", len(rows), b.Problem.Height)
+	}
+	for ri, row := range rows {
+		if len(row) != b.Problem.Width {
+			return fmt.Errorf("grid row %d has length %d (expected %d)", ri+1, len(row), b.Problem.Width)
+		}
+		for ci, ch := range row {
+			cell := Cell(UNKNOWN)
+			switch ch {
+			case 'X':
+				cell = PAINTED
+			case '.':
+				cell = CLEAR
+			case '_':
+			default:
+				return fmt.Errorf("grid has invalid cell %q at %v", ch, Coordinate{ri, ci})
+			}
+			if err := b.markInput(ri, ci, cell); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type islandJSON struct {
+	Root       *coordJSON  `json:"root,omitempty"`
+	TargetSize int         `json:"targetSize,omitempty"`
+	Size       int         `json:"size"`
+	Members    []coordJSON `json:"members"`
+}
+
+func islandsJSON(islands []*Island) []islandJSON {
+	out := make([]islandJSON, 0, len(islands))
+	for _, i := range islands {
+		ij := islandJSON{nil, i.TargetSize, i.CurrentSize, make([]coordJSON, 0, i.Members.Size())}
+		if i.IsRooted() {
+			ij.Root = &coordJSON{i.Root.Row, i.Root.Col}
+		}
+		for _, m := range i.Members.ToSlice() {
+			ij.Members = append(ij.Members, coordJSON{m.Row, m.Col})
+		}
+		out = append(out, ij)
+	}
+	return out
+}
+
+type boardJSON struct {
+	Problem     ProblemDef   `json:"problem"`
+	Grid        []string     `json:"grid"`
+	TotalMarked int          `json:"totalMarked"`
+	Islands     []islandJSON `json:"islands,omitempty"`
+	WallIslands []islandJSON `json:"wallIslands,omitempty"`
+}
+
+// MarshalJSON writes the problem, the grid and the islands derived from it.
+func (b *Board) MarshalJSON() ([]byte, error) {
+	return json.Marshal(boardJSON{b.Problem, gridRows(b.Grid), b.TotalMarked, islandsJSON(b.Islands), islandsJSON(b.WallIslands)})
+}
+
+// UnmarshalJSON rebuilds a board from its problem and grid; the islands are
+// derived again rather than read.
+func (b *Board) UnmarshalJSON(data []byte) error {
+	var bj boardJSON
+	if err := json.Unmarshal(data, &bj); err != nil {
+		return err
+	}
+	nb := BoardFromDef(bj.Problem)
+	if bj.Grid != nil {
+		if err := nb.applyGridRows(bj.Grid); err != nil {
+			return err
+		}
+	}
+	*b = *nb
+	return nil
+}
+
+// Puzzle is a problem together with the metadata and grids we store
+// alongside it.
+type Puzzle struct {
+	Title  string
+	Author string
+	Source string
+	Def    ProblemDef
+	// State is a partially solved board, or nil to start from the clues.
+	State *Board
+	// Solution is the solved board, or nil if it isn't known.
+	Solution *Board
+}
+
+type puzzleJSON struct {
+	Title    string     `json:"title,omitempty"`
+	Author   string     `json:"author,omitempty"`
+	Source   string     `json:"source,omitempty"`
+	Width    int        `json:"width"`
+	Height   int        `json:"height"`
+	Clues    []clueJSON `json:"clues"`
+	Grid     []string   `json:"grid,omitempty"`
+	Solution []string   `json:"solution,omitempty"`
+}
+
+func (p *Puzzle) MarshalJSON() ([]byte, error) {
+	pj := p.Def.toJSON()
+	out := puzzleJSON{p.Title, p.Author, p.Source, pj.Width, pj.Height, pj.Clues, nil, nil}
+	if p.State != nil {
+		out.Grid = gridRows(p.State.Grid)
+	}
+	if p.Solution != nil {
+		out.Solution = gridRows(p.Solution.Grid)
+	}
+	return json.Marshal(out)
+}
+
+func (p *Puzzle) UnmarshalJSON(data []byte) error {
+	var pj puzzleJSON
+	if err := json.Unmarshal(data, &pj); err != nil {
+		return err
+	}
+	def, err := problemJSON{pj.Width, pj.Height, pj.Clues}.toDef()
+	if err != nil {
+		return err
+	}
+	np := Puzzle{pj.Title, pj.Author, pj.Source, def, nil, nil}
+	if pj.Grid != nil {
+		np.State = BoardFromDef(def)
+		if err := np.State.applyGridRows(pj.Grid); err != nil {
+			return err
+		}
+	}
+	if pj.Solution != nil {
+		np.Solution = BoardFromDef(def)
+		if err := np.Solution.applyGridRows(pj.Solution); err != nil {
+			return fmt.Errorf("solution: %v", err)
+		}
+	}
+	*p = np
+	return nil
+}
+
+// Board returns the puzzle's starting board: a copy of State if there is
+// one, or a fresh board with just the clues.
+func (p *Puzzle) Board() *Board {
+	if p.State != nil {
+		return p.State.Clone()
+	}
+	return BoardFromDef(p.Def)
+}
+
+// IsJSON reports whether input looks like a JSON puzzle.
+func IsJSON(input string) bool {
+	return strings.HasPrefix(strings.TrimSpace(input), "{")
+}
+
+// PuzzleFromJSON reads a puzzle in the format written by Puzzle.MarshalJSON.
+func PuzzleFromJSON(input string) (*Puzzle, error) {
+	var p Puzzle
+	if err := json.Unmarshal([]byte(input), &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+type resultJSON struct {
+	Solved         bool    `json:"solved"`
+	Reason         string  `json:"reason,omitempty"`
+	ElapsedSeconds float64 `json:"elapsedSeconds"`
+	Board          *Board  `json:"board"`
+	Trace          *Trace  `json:"trace,omitempty"`
+}
+
+// MarshalJSON writes the result with its failure reason as a string and its
+// elapsed time in seconds.
+func (r *Result) MarshalJSON() ([]byte, error) {
+	rj := resultJSON{r.Solved, "", r.Elapsed.Seconds(), r.Board, r.Trace}
+	if r.Reason != nil {
+		rj.Reason = r.Reason.Error()
+	}
+	return json.Marshal(rj)
+}
@@ -0,0 +1,40 @@
+package nurigobe
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPuzzleFromJSONPaintedClue(t *testing.T) {
+	input := `{"width":2,"height":2,"clues":[{"row":0,"col":0,"size":1}],"grid":["X_","__"]}`
+	if p, err := PuzzleFromJSON(input); err == nil {
+		t.Fatalf("expected an error, got state\n%v", p.State)
+	}
+	input = `{"width":2,"height":2,"clues":[{"row":0,"col":0,"size":1}],"grid":["._","_X"]}`
+	p, err := PuzzleFromJSON(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.State.TotalMarked != 2 {
+		t.Fatalf("got %d cells marked, want 2", p.State.TotalMarked)
+	}
+}
+
+func TestGameBoardPaintedClue(t *testing.T) {
+	g := NewGame(context.Background(), readProblem(t, "problem1.txt"))
+	spec := g.Problem.IslandSpecs[0]
+	g.Grid[spec.Row][spec.Col] = PAINTED
+	if b, err := g.Board(); err == nil {
+		t.Fatalf("expected an error, got\n%v", b)
+	}
+	if _, err := g.Hint(context.Background()); err == nil {
+		t.Fatalf("expected Hint to fail on a painted clue")
+	}
+}
+
+func TestPuzzleFromJSONDuplicateClue(t *testing.T) {
+	input := `{"width":3,"height":2,"clues":[{"row":0,"col":1,"size":2},{"row":0,"col":1,"size":1}]}`
+	if p, err := PuzzleFromJSON(input); err == nil {
+		t.Fatalf("expected an error, got problem\n%v", p.Def)
+	}
+}
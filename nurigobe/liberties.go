@@ -0,0 +1,96 @@
+package nurigobe
+
+// takeLiberty removes the newly marked cell c from the liberties of the
+// islands and wall islands next to it.
+func (b *Board) takeLiberty(c Coordinate) {
+	for _, n := range []Coordinate{c.Translate(-1, 0), c.Translate(1, 0), c.Translate(0, -1), c.Translate(0, 1)} {
+		if !b.IsInBounds(n) {
+			continue
+		}
+		for _, i := range []*Island{b.islandCells.get(b.cellIndex(n)), b.wallCells.get(b.cellIndex(n))} {
+			if i == nil || !i.Liberties.Contains(c) {
+				continue
+			}
+			b.recordSet(i.Liberties)
+			i.Liberties.Del(c)
+			b.queueLiberties(i)
+		}
+	}
+}
+
+// onBoard reports whether i is still one of b's islands or wall islands,
+// rather than merged into another.
+func (b *Board) onBoard(i *Island) bool {
+	cells := b.islandCells
+	if i.IslandType == WALL_ISLAND {
+		cells = b.wallCells
+	}
+	return cells.get(b.cellIndex(i.Members.First())) == i
+}
+
+func (b *Board) libertyQueue(i *Island) *[]*Island {
+	if i.IslandType == WALL_ISLAND {
+		return &b.wallQueue
+	}
+	return &b.islandQueue
+}
+
+// queueLiberties lists i for the one-liberty rules.
+func (b *Board) queueLiberties(i *Island) {
+	b.queueIsland(b.libertyQueue(i), i)
+}
+
+// queueIsland adds i to the end of queue unless it is already last. When
+// the queue fills up, islands listed more than once are dropped first,
+// keeping the first of each.
+func (b *Board) queueIsland(queue *[]*Island, i *Island) {
+	if len(*queue) > 0 && (*queue)[len(*queue)-1] == i {
+		return
+	}
+	saved := *queue
+	b.trail.record(func() { *queue = saved })
+	if len(*queue) >= 16 && len(*queue) == cap(*queue) {
+		seen := make(map[*Island]bool, len(*queue))
+		deduped := make([]*Island, 0, len(*queue))
+		for _, q := range *queue {
+			if !seen[q] {
+				seen[q] = true
+				deduped = append(deduped, q)
+			}
+		}
+		*queue = append(make([]*Island, 0, 2*len(deduped)), deduped...)
+	}
+	*queue = append(*queue, i)
+}
+
+// nextLibertyChange returns the first island in queue that is still on the
+// board, without removing it, or nil if there is none. Islands since merged
+// into others are dropped.
+func (b *Board) nextLibertyChange(queue *[]*Island) *Island {
+	for len(*queue) > 0 {
+		if i := (*queue)[0]; b.onBoard(i) {
+			return i
+		}
+		b.dropLibertyChange(queue)
+	}
+	return nil
+}
+
+// dropLibertyChange removes the first island in queue.
+func (b *Board) dropLibertyChange(queue *[]*Island) {
+	saved := *queue
+	b.trail.record(func() { *queue = saved })
+	*queue = (*queue)[1:]
+}
+
+// cloneQueue copies queue, replacing each island with its clone and leaving
+// out islands that are no longer on the board.
+func cloneQueue(queue []*Island, islands map[*Island]*Island) []*Island {
+	new := make([]*Island, 0, len(queue))
+	for _, i := range queue {
+		if c, ok := islands[i]; ok {
+			new = append(new, c)
+		}
+	}
+	return new
+}
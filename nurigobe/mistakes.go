@@ -5,32 +5,32 @@ import "fmt"
 // Would the proposed CoordinateSet, if entered in the problem as an island,
 // necessarily force the walls to be split into two (or more) wall islands?
 func (b *Board) SetSplitsWalls(cs *CoordinateSet) bool {
-	Watch.Start("SetSplitsWalls")
-	defer Watch.Stop("SetSplitsWalls")
+	b.Watch.Start("SetSplitsWalls")
+	defer b.Watch.Stop("SetSplitsWalls")
 
 	//Start by merging the coordinate set with the CSes of all islands it
 	//borders diagnoally
-	Watch.Start("Merge for set splitting")
+	b.Watch.Start("Merge for set splitting")
 	b.ClearScratchGrid()
 	const BLOCKED = PAINTED
 	const COVERED = CLEAR
 	unkCt := b.Problem.Size
 
-	for c := range cs.Map {
+	for _, c := range cs.ToSlice() {
 		b.ScratchGrid[c.Row][c.Col] = BLOCKED
 		unkCt--
 	}
 	for _, s := range b.DiagonalSets {
 		if s.BordersSetDiagonally(cs) {
-			for c := range s.Map {
+			for _, c := range s.ToSlice() {
 				b.ScratchGrid[c.Row][c.Col] = BLOCKED
 				unkCt--
 			}
 		}
 	}
-	Watch.Stop("Merge for set splitting")
+	b.Watch.Stop("Merge for set splitting")
 
-	Watch.Start("Border walk")
+	b.Watch.Start("Border walk")
 	//To detect a wall-splitting island, we can visit each cell on the puzzle's
 	//border in turn, starting at {0,0} and continuing clockwise. As we visit
 	//each cell, we check whether the cell is part of the proposed coordinate
@@ -70,7 +70,7 @@ func (b *Board) SetSplitsWalls(cs *CoordinateSet) bool {
 		if isMember != wasLastMember {
 			changes++
 			if changes > 2 {
-				Watch.Stop("Border walk")
+				b.Watch.Stop("Border walk")
 				return true
 			}
 			wasLastMember = isMember
@@ -80,9 +80,9 @@ func (b *Board) SetSplitsWalls(cs *CoordinateSet) bool {
 			break
 		}
 	}
-	Watch.Stop("Border walk")
+	b.Watch.Stop("Border walk")
 
-	Watch.Start("Interior wall isolation")
+	b.Watch.Start("Interior wall isolation")
 	//To detect an island that would isolate an interior wall island - e.g., this:
 	//
 	// ___...___
@@ -133,14 +133,14 @@ func (b *Board) SetSplitsWalls(cs *CoordinateSet) bool {
 			break
 		}
 	}
-	Watch.Stop("Interior wall isolation")
+	b.Watch.Stop("Interior wall isolation")
 
 	return unkCt > 0
 }
 
 func (b *Board) ContainsError() error {
-	Watch.Start("Contains error")
-	defer Watch.Stop("Contains error")
+	b.Watch.Start("Contains error")
+	defer b.Watch.Stop("Contains error")
 	for r := 0; r < b.Problem.Height; r++ {
 		for c := 0; c < b.Problem.Width; c++ {
 			if b.IsPool(r, c) {
@@ -0,0 +1,196 @@
+package nurigobe
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"strconv"
+)
+
+// ImageOptions controls the output of Board.Image and Board.WritePNG.
+type ImageOptions struct {
+	// CellSize is the width and height of each cell in pixels.
+	CellSize       int
+	UnknownColor   color.Color
+	WallColor      color.Color
+	ClearColor     color.Color
+	GridColor      color.Color
+	ClueColor      color.Color
+	HighlightColor color.Color
+	// Highlight is a set of cells to shade, or nil.
+	Highlight *CoordinateSet
+}
+
+func DefaultImageOptions() ImageOptions {
+	return ImageOptions{
+		32,
+		color.RGBA{0xff, 0xff, 0xff, 0xff},
+		color.RGBA{0x33, 0x33, 0x33, 0xff},
+		color.RGBA{0xf4, 0xf4, 0xf4, 0xff},
+		color.RGBA{0x99, 0x99, 0x99, 0xff},
+		color.RGBA{0x00, 0x00, 0x00, 0xff},
+		color.RGBA{0xff, 0xd5, 0x4f, 0xff},
+		nil,
+	}
+}
+
+// Image draws the board with one CellSize square per cell, a one-pixel grid
+// and a two-pixel border.
+func (b *Board) Image(opts ImageOptions) *image.RGBA {
+	cs := opts.CellSize
+	if cs < 4 {
+		cs = 4
+	}
+	width := b.Problem.Width*cs + 3
+	height := b.Problem.Height*cs + 3
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(opts.GridColor), image.Point{}, draw.Src)
+	clues := make(map[Coordinate]int, len(b.Problem.IslandSpecs))
+	for _, spec := range b.Problem.IslandSpecs {
+		clues[Coordinate{spec.Row, spec.Col}] = spec.Size
+	}
+	for r := 0; r < b.Problem.Height; r++ {
+		for c := 0; c < b.Problem.Width; c++ {
+			cell := image.Rect(c*cs+2, r*cs+2, (c+1)*cs+1, (r+1)*cs+1)
+			fill := opts.UnknownColor
+			switch b.Grid[r][c] {
+			case PAINTED:
+				fill = opts.WallColor
+			case CLEAR:
+				fill = opts.ClearColor
+			}
+			highlighted := opts.Highlight != nil && opts.Highlight.Contains(Coordinate{r, c})
+			if highlighted {
+				fillRect(img, cell, opts.HighlightColor)
+				if b.Grid[r][c] == PAINTED {
+					fillRect(img, cell.Inset(cs/8), fill)
+				}
+			} else {
+				fillRect(img, cell, fill)
+			}
+			if size, ok := clues[Coordinate{r, c}]; ok {
+				drawCentered(img, cell, strconv.Itoa(size), opts.ClueColor)
+			} else if b.Grid[r][c] == CLEAR {
+				dot := cs/10 + 1
+				mid := cell.Min.Add(image.Pt(cell.Dx()/2, cell.Dy()/2))
+				fillRect(img, image.Rect(mid.X-dot/2, mid.Y-dot/2, mid.X-dot/2+dot, mid.Y-dot/2+dot), opts.WallColor)
+			}
+		}
+	}
+	border := image.NewUniform(opts.ClueColor)
+	for _, edge := range []image.Rectangle{
+		image.Rect(0, 0, width, 2),
+		image.Rect(0, height-2, width, height),
+		image.Rect(0, 0, 2, height),
+		image.Rect(width-2, 0, width, height),
+	} {
+		draw.Draw(img, edge, border, image.Point{}, draw.Src)
+	}
+	return img
+}
+
+// WritePNG encodes the board's Image as a PNG.
+func (b *Board) WritePNG(w io.Writer, opts ImageOptions) error {
+	return png.Encode(w, b.Image(opts))
+}
+
+func fillRect(img draw.Image, r image.Rectangle, c color.Color) {
+	draw.Draw(img, r, image.NewUniform(c), image.Point{}, draw.Src)
+}
+
+// glyphs is a 3x5 bitmap font of digits, capital letters and a little
+// punctuation; each string is one row and '#' is a set pixel.
+var glyphs = map[rune][5]string{
+	'0': {"###", "#.#", "#.#", "#.#", "###"},
+	'1': {".#.", "##.", ".#.", ".#.", "###"},
+	'2': {"###", "..#", "###", "#..", "###"},
+	'3': {"###", "..#", "###", "..#", "###"},
+	'4': {"#.#", "#.#", "###", "..#", "..#"},
+	'5': {"###", "#..", "###", "..#", "###"},
+	'6': {"###", "#..", "###", "#.#", "###"},
+	'7': {"###", "..#", "..#", ".#.", ".#."},
+	'8': {"###", "#.#", "###", "#.#", "###"},
+	'9': {"###", "#.#", "###", "..#", "###"},
+	'?': {"###", "..#", ".##", "...", ".#."},
+	'A': {".#.", "#.#", "###", "#.#", "#.#"},
+	'B': {"##.", "#.#", "##.", "#.#", "##."},
+	'C': {".##", "#..", "#..", "#..", ".##"},
+	'D': {"##.", "#.#", "#.#", "#.#", "##."},
+	'E': {"###", "#..", "##.", "#..", "###"},
+	'F': {"###", "#..", "##.", "#..", "#.."},
+	'G': {".##", "#..", "#.#", "#.#", ".##"},
+	'H': {"#.#", "#.#", "###", "#.#", "#.#"},
+	'I': {"###", ".#.", ".#.", ".#.", "###"},
+	'J': {"..#", "..#", "..#", "#.#", ".#."},
+	'K': {"#.#", "#.#", "##.", "#.#", "#.#"},
+	'L': {"#..", "#..", "#..", "#..", "###"},
+	'M': {"#.#", "###", "###", "#.#", "#.#"},
+	'N': {"##.", "#.#", "#.#", "#.#", "#.#"},
+	'O': {".#.", "#.#", "#.#", "#.#", ".#."},
+	'P': {"##.", "#.#", "##.", "#..", "#.."},
+	'Q': {".#.", "#.#", "#.#", "##.", ".##"},
+	'R': {"##.", "#.#", "##.", "#.#", "#.#"},
+	'S': {".##", "#..", ".#.", "..#", "##."},
+	'T': {"###", ".#.", ".#.", ".#.", ".#."},
+	'U': {"#.#", "#.#", "#.#", "#.#", "###"},
+	'V': {"#.#", "#.#", "#.#", "#.#", ".#."},
+	'W': {"#.#", "#.#", "###", "###", "#.#"},
+	'X': {"#.#", "#.#", ".#.", "#.#", "#.#"},
+	'Y': {"#.#", "#.#", ".#.", ".#.", ".#."},
+	'Z': {"###", "..#", ".#.", "#..", "###"},
+	'(': {"..#", ".#.", ".#.", ".#.", "..#"},
+	')': {"#..", ".#.", ".#.", ".#.", "#.."},
+	'-': {"...", "...", "###", "...", "..."},
+	'.': {"...", "...", "...", "...", ".#."},
+	',': {"...", "...", "...", ".#.", "#.."},
+	':': {"...", ".#.", "...", ".#.", "..."},
+	'/': {"..#", "..#", ".#.", "#..", "#.."},
+}
+
+const glyphWidth = 3
+const glyphHeight = 5
+
+// textWidth is the width of s in font pixels, including one pixel between
+// characters.
+func textWidth(s string) int {
+	n := len([]rune(s))
+	if n == 0 {
+		return 0
+	}
+	return n*(glyphWidth+1) - 1
+}
+
+// drawText draws s with its top-left corner at p, scaling each font pixel to
+// a scale x scale square. Characters missing from the font are left blank.
+func drawText(img draw.Image, p image.Point, scale int, s string, c color.Color) {
+	x := p.X
+	for _, ch := range s {
+		g := glyphs[ch]
+		for gy, row := range g {
+			for gx, px := range row {
+				if px != '#' {
+					continue
+				}
+				fillRect(img, image.Rect(x+gx*scale, p.Y+gy*scale, x+(gx+1)*scale, p.Y+(gy+1)*scale), c)
+			}
+		}
+		x += (glyphWidth + 1) * scale
+	}
+}
+
+// drawCentered draws s as large as it fits in about 60% of the height of r
+// and most of its width, centered in r.
+func drawCentered(img draw.Image, r image.Rectangle, s string, c color.Color) {
+	scale := r.Dy() * 3 / 5 / glyphHeight
+	if ws := (r.Dx() - 4) / textWidth(s); ws < scale {
+		scale = ws
+	}
+	if scale < 1 {
+		scale = 1
+	}
+	w := textWidth(s) * scale
+	h := glyphHeight * scale
+	drawText(img, image.Pt(r.Min.X+(r.Dx()-w)/2, r.Min.Y+(r.Dy()-h)/2), scale, s, c)
+}
@@ -7,14 +7,20 @@ import (
 )
 
 func (s *Solver) PrintUpdates(wg *sync.WaitGroup) {
+	PrintProgress(s.Progress, wg)
+}
+
+// PrintProgress draws a progress bar on the terminal for every update read
+// from c until c is closed.
+func PrintProgress(c <-chan ProgressUpdate, wg *sync.WaitGroup) {
 	defer wg.Done()
-	if s.Progress == nil {
+	if c == nil {
 		return
 	}
 	fmt.Println("Starting...")
 	for {
 		select {
-		case update, ok := <-s.Progress:
+		case update, ok := <-c:
 			if !ok {
 				return
 			}
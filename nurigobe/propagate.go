@@ -0,0 +1,164 @@
+package nurigobe
+
+// queueMark lists the newly marked cell c for propagation.
+func (b *Board) queueMark(c Coordinate) {
+	saved := b.markQueue
+	b.trail.record(func() { b.markQueue = saved })
+	b.markQueue = append(b.markQueue, c)
+}
+
+// nextMark removes and returns the first cell waiting for propagation.
+func (b *Board) nextMark() (Coordinate, bool) {
+	if len(b.markQueue) == 0 {
+		return NilCoordinate(), false
+	}
+	saved := b.markQueue
+	b.trail.record(func() { b.markQueue = saved })
+	c := b.markQueue[0]
+	b.markQueue = b.markQueue[1:]
+	return c, true
+}
+
+// queuePossibilities lists i, whose possibilities have changed, for
+// propagation.
+func (b *Board) queuePossibilities(i *Island) {
+	b.queueIsland(&b.possibilityQueue, i)
+}
+
+// takePossibilityChanges empties the possibility queue and returns what was
+// in it.
+func (b *Board) takePossibilityChanges() []*Island {
+	saved := b.possibilityQueue
+	b.trail.record(func() { b.possibilityQueue = saved })
+	b.possibilityQueue = nil
+	return saved
+}
+
+// discardPropagation empties the mark and possibility queues, for callers
+// that run every rule over the whole board instead of propagating.
+func (b *Board) discardPropagation() {
+	marks, possibilities := b.markQueue, b.possibilityQueue
+	b.trail.record(func() {
+		b.markQueue = marks
+		b.possibilityQueue = possibilities
+	})
+	b.markQueue = nil
+	b.possibilityQueue = nil
+}
+
+// propagate applies the rules that only depend on the area around a change
+// to the cells marked, and the islands whose possibilities changed, since it
+// last ran, until the marks they make stop leading to more. It reports
+// whether it marked anything. The possibility rules wait for InitSolve.
+// Propagation stops early at a contradiction, leaving the rest queued, and
+// while stepping through NextDeduction it stops at the first mark.
+func (s *Solver) propagate() bool {
+	s.b.Watch.Start("Propagate")
+	defer s.b.Watch.Stop("Propagate")
+	changed := false
+	for !s.Cancelled() && s.next == nil {
+		if c, ok := s.b.nextMark(); ok {
+			if s.contradictionAt(c) {
+				break
+			}
+			changed = s.propagateMark(c) || changed
+		} else if s.initialized && len(s.b.possibilityQueue) > 0 {
+			marked, contradiction := s.propagatePossibilities()
+			changed = marked || changed
+			if contradiction {
+				break
+			}
+		} else {
+			break
+		}
+	}
+	return changed
+}
+
+// contradictionAt reports whether marking c made a pool or left c's island
+// too big or, once possibilities are known, without any.
+func (s *Solver) contradictionAt(c Coordinate) bool {
+	for r := c.Row - 1; r <= c.Row; r++ {
+		for col := c.Col - 1; col <= c.Col; col++ {
+			if r >= 0 && col >= 0 && s.b.IsPool(r, col) {
+				return true
+			}
+		}
+	}
+	i := s.b.IslandAt(c.Row, c.Col)
+	if i == nil || i.IsComplete() {
+		return false
+	}
+	return (i.IsRooted() && i.CurrentSize > i.TargetSize) || (s.initialized && len(i.Possibilities) == 0)
+}
+
+// propagateMark fills the elbows in the 2x2 squares around c and, if c is
+// clear, paints the two-bordered liberties of its island and borders the
+// island if it is complete, then extends the islands whose liberties have
+// changed.
+func (s *Solver) propagateMark(c Coordinate) bool {
+	changed := false
+	for r := c.Row - 1; r <= c.Row; r++ {
+		for col := c.Col - 1; col <= c.Col; col++ {
+			if r >= 0 && col >= 0 && r < s.b.Problem.Height-1 && col < s.b.Problem.Width-1 {
+				changed = s.fillElbowAt(r, col) || changed
+			}
+		}
+	}
+	if island := s.b.IslandAt(c.Row, c.Col); island != nil {
+		for _, l := range island.Liberties.ToSlice() {
+			changed = s.paintIfTwoBordered(l) || changed
+		}
+		changed = s.addBordersTo(island) || changed
+	}
+	if len(s.b.islandQueue) > 0 {
+		changed = s.ExtendIslandsOneLiberty() || changed
+	}
+	if len(s.b.wallQueue) > 0 {
+		changed = s.ExtendWallIslandsOneLiberty() || changed
+	}
+	return changed
+}
+
+// propagatePossibilities brings the reachable cells of the islands whose
+// possibilities changed up to date, paints the cells no island can reach any
+// more and fills the necessary cells of those islands. It stops, reporting
+// a contradiction, if one of the islands has no possibilities left.
+func (s *Solver) propagatePossibilities() (bool, bool) {
+	dropped := EmptyCoordinateSet()
+	islands := make([]*Island, 0)
+	seen := make(map[*Island]bool)
+	for _, i := range s.b.takePossibilityChanges() {
+		old := i.Reachable
+		if !s.b.onBoard(i) {
+			//merged into another island, which is queued too
+			if old != nil {
+				dropped.AddAll(old)
+			}
+			continue
+		}
+		if seen[i] {
+			continue
+		}
+		if !i.IsComplete() && len(i.Possibilities) == 0 {
+			return false, true
+		}
+		seen[i] = true
+		s.b.recordIsland(i)
+		i.PopulateReachables()
+		if old != nil {
+			dropped.AddAll(old.Minus(i.Reachable))
+		}
+		islands = append(islands, i)
+	}
+	changed := false
+	for _, c := range dropped.ToSlice() {
+		changed = s.paintIfUnreachable(c) || changed
+	}
+	for _, i := range islands {
+		if s.b.onBoard(i) {
+			changed = s.fillNecessariesFor(i) || changed
+		}
+	}
+	return changed, false
+}
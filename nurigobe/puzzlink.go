@@ -0,0 +1,145 @@
+package nurigobe
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PuzzLinkPrefix is the start of the URLs produced by PuzzLinkURL.
+const PuzzLinkPrefix = "https://puzz.link/p?"
+
+// IsPuzzLinkURL reports whether s looks like a puzz.link or pzv.jp URL rather
+// than a file name.
+func IsPuzzLinkURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://") || strings.HasPrefix(s, "p?")
+}
+
+// DefFromPuzzLink decodes a pzprjs nurikabe URL such as
+// https://puzz.link/p?nurikabe/10/10/... into a ProblemDef.
+func DefFromPuzzLink(url string) (ProblemDef, error) {
+	q := strings.Index(url, "?")
+	if q < 0 {
+		return ProblemDef{}, fmt.Errorf("puzzle URL %q has no query", url)
+	}
+	parts := strings.Split(url[q+1:], "/")
+	if parts[0] != "nurikabe" {
+		return ProblemDef{}, fmt.Errorf("puzzle URL is for %q, not nurikabe", parts[0])
+	}
+	parts = parts[1:]
+	//pzprjs may insert a variant flag such as "v:" before the dimensions
+	if len(parts) > 0 && strings.HasSuffix(parts[0], ":") {
+		parts = parts[1:]
+	}
+	if len(parts) < 3 {
+		return ProblemDef{}, fmt.Errorf("puzzle URL %q is missing its dimensions or body", url)
+	}
+	w, err := strconv.Atoi(parts[0])
+	if err != nil || w < 1 {
+		return ProblemDef{}, fmt.Errorf("puzzle URL has invalid width %q", parts[0])
+	}
+	h, err := strconv.Atoi(parts[1])
+	if err != nil || h < 1 {
+		return ProblemDef{}, fmt.Errorf("puzzle URL has invalid height %q", parts[1])
+	}
+	prob := ProblemDef{w, h, w * h, nil, 0}
+	if err := prob.decodeNumber16(parts[2]); err != nil {
+		return ProblemDef{}, err
+	}
+	return prob, nil
+}
+
+// decodeNumber16 reads pzprjs's number encoding: a hex digit is a clue from 0
+// to 15, '-' is followed by two hex digits, '+' by three, '=' and '%' by
+// three more with 4096 or 8192 added, '.' is an unknown clue and 'g' through
+// 'z' skip 1 to 20 cells.
+func (p *ProblemDef) decodeNumber16(body string) error {
+	cell := 0
+	for i := 0; i < len(body) && cell < p.Size; i++ {
+		ch := body[i]
+		size := -1
+		digits := 0
+		offset := 0
+		switch {
+		case ch >= '0' && ch <= '9', ch >= 'a' && ch <= 'f':
+			v, _ := strconv.ParseInt(string(ch), 16, 32)
+			size = int(v)
+		case ch == '-':
+			digits = 2
+		case ch == '+':
+			digits = 3
+		case ch == '=':
+			digits, offset = 3, 4096
+		case ch == '%':
+			digits, offset = 3, 8192
+		case ch == '.':
+			return fmt.Errorf("puzzle URL has a clue without a number at %v, which is not supported", Coordinate{cell / p.Width, cell % p.Width})
+		case ch >= 'g' && ch <= 'z':
+			v, _ := strconv.ParseInt(string(ch), 36, 32)
+			cell += int(v) - 16
+		default:
+			return fmt.Errorf("puzzle URL has unexpected character %q", ch)
+		}
+		if digits > 0 {
+			if i+digits >= len(body) {
+				return fmt.Errorf("puzzle URL ends in the middle of a number")
+			}
+			v, err := strconv.ParseInt(body[i+1:i+1+digits], 16, 32)
+			if err != nil {
+				return fmt.Errorf("puzzle URL has invalid number %q", body[i+1:i+1+digits])
+			}
+			size = int(v) + offset
+			i += digits
+		}
+		if size > 0 {
+			p.IslandSpecs = append(p.IslandSpecs, IslandSpec{cell % p.Width, cell / p.Width, size})
+			p.TargetWallCount += size
+		} else if size == 0 {
+			return fmt.Errorf("puzzle URL has a zero clue at %v", Coordinate{cell / p.Width, cell % p.Width})
+		}
+		cell++
+	}
+	return nil
+}
+
+// PuzzLinkURL encodes the problem as a puzz.link URL.
+func (p ProblemDef) PuzzLinkURL() string {
+	clues := make(map[int]int, len(p.IslandSpecs))
+	for _, spec := range p.IslandSpecs {
+		clues[spec.Row*p.Width+spec.Col] = spec.Size
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%snurikabe/%d/%d/", PuzzLinkPrefix, p.Width, p.Height))
+	skipped := 0
+	for cell := 0; cell < p.Width*p.Height; cell++ {
+		size, ok := clues[cell]
+		if !ok {
+			skipped++
+			if skipped == 20 {
+				sb.WriteByte('z')
+				skipped = 0
+			}
+			continue
+		}
+		if skipped > 0 {
+			sb.WriteString(strconv.FormatInt(int64(15+skipped), 36))
+			skipped = 0
+		}
+		switch {
+		case size < 16:
+			sb.WriteString(strconv.FormatInt(int64(size), 16))
+		case size < 256:
+			sb.WriteString("-" + strconv.FormatInt(int64(size), 16))
+		case size < 4096:
+			sb.WriteString("+" + strconv.FormatInt(int64(size), 16))
+		case size < 8192:
+			sb.WriteString("=" + fmt.Sprintf("%03x", size-4096))
+		default:
+			sb.WriteString("%" + fmt.Sprintf("%03x", size-8192))
+		}
+	}
+	if skipped > 0 {
+		sb.WriteString(strconv.FormatInt(int64(15+skipped), 36))
+	}
+	return sb.String()
+}
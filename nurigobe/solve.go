@@ -0,0 +1,92 @@
+package nurigobe
+
+import (
+	"context"
+	"runtime"
+	"time"
+)
+
+// Options controls how Solve attacks a puzzle.
+type Options struct {
+	// MakeGuesses enables hypothesis testing (MakeAGuess) once the
+	// deductive rules stop making progress.
+	MakeGuesses bool
+	// SkipExpensive disables EliminateIntolerables and
+	// EliminateWallSplitters and keeps guesses shallow.
+	SkipExpensive bool
+	// Progress, if non-nil, receives a ProgressUpdate for every action and
+	// every marked cell. Solve closes it before returning.
+	Progress chan ProgressUpdate
+	// Trace asks Solve to record every deduction in Result.Trace.
+	Trace bool
+	// Record asks Solve to record every marked cell in Result.Recording,
+	// e.g. to animate the solve with Recording.GIF.
+	Record bool
+	// GuessWorkers, if greater than one, is the number of guesses tested at
+	// once; the first contradiction found wins.
+	GuessWorkers int
+	// DeterministicGuesses makes parallel guessing pick the same cell as
+	// testing one guess at a time, so traces are reproducible.
+	DeterministicGuesses bool
+	// MaxSteps, if positive, gives up after that many passes through the
+	// rules and guesses tested. Unlike a deadline, it stops at the same point
+	// on every machine.
+	MaxSteps int
+}
+
+// DefaultOptions returns the options the command-line solver uses.
+func DefaultOptions() Options {
+	return Options{MakeGuesses: true, GuessWorkers: runtime.NumCPU()}
+}
+
+// Result is the outcome of a call to Solve.
+type Result struct {
+	Board  *Board
+	Solved bool
+	// Reason explains why the board is not solved; it is nil when Solved
+	// is true.
+	Reason error
+	// Trace lists the deductions made, if Options.Trace was set.
+	Trace *Trace
+	// Elapsed is how long the solve took.
+	Elapsed time.Duration
+	// Recording holds the marked cells in order, if Options.Record was set.
+	Recording *Recording
+}
+
+// Solve builds a board from def and solves it as far as the solver can.
+// The returned error is non-nil only if ctx was cancelled or its deadline
+// passed, in which case the Result holds the partially solved board.
+func Solve(ctx context.Context, def ProblemDef, opts Options) (*Result, error) {
+	return SolveBoard(ctx, BoardFromDef(def), opts)
+}
+
+// SolveBoard is like Solve but starts from an existing, possibly partially
+// marked board. The board is modified in place.
+func SolveBoard(ctx context.Context, b *Board, opts Options) (*Result, error) {
+	start := time.Now()
+	s := NewSolverWithContext(ctx, b)
+	s.Progress = opts.Progress
+	s.GuessWorkers = opts.GuessWorkers
+	s.DeterministicGuesses = opts.DeterministicGuesses
+	s.MaxSteps = opts.MaxSteps
+	if s.Progress != nil {
+		defer close(s.Progress)
+	}
+	if opts.Trace {
+		s.Trace = NewTrace()
+	}
+	if opts.Record {
+		s.Recording = NewRecording(b)
+	}
+	s.InitSolve()
+	if !s.Cancelled() {
+		s.AutoSolve(opts.MakeGuesses, opts.SkipExpensive)
+	}
+	res := &Result{Board: b, Trace: s.Trace, Elapsed: time.Since(start), Recording: s.Recording}
+	res.Solved, res.Reason = b.IsSolved()
+	if err := ctx.Err(); err != nil {
+		return res, err
+	}
+	return res, nil
+}
@@ -0,0 +1,57 @@
+package nurigobe
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+var testProblems = []string{"problem1.txt", "problem2.txt", "problem3.txt", "problem4.txt"}
+
+func readProblem(tb testing.TB, name string) *Board {
+	b, err := GetBoardFromFile(filepath.Join("..", name))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return b
+}
+
+// TestSolveConcurrently solves every problem and a clone of it at the same
+// time, each guessing in parallel, so that the race detector can catch
+// state shared between boards, clones and guess workers.
+func TestSolveConcurrently(t *testing.T) {
+	var wg sync.WaitGroup
+	for _, name := range testProblems {
+		b := readProblem(t, name)
+		for _, board := range []*Board{b, b.Clone()} {
+			wg.Add(1)
+			go func(name string, b *Board) {
+				defer wg.Done()
+				res, err := SolveBoard(context.Background(), b, Options{MakeGuesses: true, GuessWorkers: 4})
+				if err != nil {
+					t.Errorf("%s: %v", name, err)
+				} else if !res.Solved {
+					t.Errorf("%s: not solved: %v\n%v", name, res.Reason, res.Board)
+				}
+			}(name, board)
+		}
+	}
+	wg.Wait()
+}
+
+func benchmarkSolve(b *testing.B, name string) {
+	def := readProblem(b, name).Problem
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		res, err := SolveBoard(context.Background(), BoardFromDef(def), Options{MakeGuesses: true})
+		if err != nil || !res.Solved {
+			b.Fatalf("%s: not solved: %v %v", name, err, res.Reason)
+		}
+	}
+}
+
+func BenchmarkSolveProblem1(b *testing.B) { benchmarkSolve(b, "problem1.txt") }
+func BenchmarkSolveProblem2(b *testing.B) { benchmarkSolve(b, "problem2.txt") }
+func BenchmarkSolveProblem3(b *testing.B) { benchmarkSolve(b, "problem3.txt") }
+func BenchmarkSolveProblem4(b *testing.B) { benchmarkSolve(b, "problem4.txt") }
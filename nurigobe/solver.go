@@ -1,135 +1,266 @@
 package nurigobe
 
 import (
+	"context"
 	"fmt"
-	"os"
+	"strings"
+	"sync"
 )
 
 type ProgressUpdate struct {
 	CurrentAction string
 	TotalMarked   int
 	GridSize      int
+	// Marked is the cell whose marking triggered the update and Cell its new
+	// colour; for updates that only change the action, Marked is
+	// NilCoordinate() and Cell is UNKNOWN.
+	Marked Coordinate
+	Cell   Cell
 }
 
 type Solver struct {
-	b        *Board
-	solution *Board
-	Action   string
-	Progress chan ProgressUpdate
+	b           *Board
+	solution    *Board
+	ctx         context.Context
+	initialized bool
+	rule        Rule
+	stepping    bool
+	next        *Deduction
+	steps       int
+	Action      string
+	Progress    chan ProgressUpdate
+	// Trace, if non-nil, receives every cell the solver marks.
+	Trace *Trace
+	// Recording, if non-nil, receives every cell the solver marks along
+	// with the current Action.
+	Recording *Recording
+	// GuessWorkers, if greater than one, is the number of guesses
+	// MakeAGuess tests at once.
+	GuessWorkers int
+	// DeterministicGuesses makes parallel guessing mark the same cell the
+	// sequential scan would, at the cost of waiting for earlier cells.
+	DeterministicGuesses bool
+	// MaxSteps, if positive, stops the solver after that many steps, a step
+	// being a pass through the rules or a guess tested by MakeAGuess.
+	MaxSteps int
 }
 
 func NewSolver(b *Board) *Solver {
-    s := Solver{b, nil, "", make(chan ProgressUpdate, b.Problem.Size*2)}
-    return &s
+	return NewSolverWithContext(context.Background(), b)
 }
 
+// NewSolverWithContext returns a solver that stops working on b as soon as
+// ctx is cancelled or its deadline passes.
+func NewSolverWithContext(ctx context.Context, b *Board) *Solver {
+	s := Solver{b, nil, ctx, false, "", false, nil, 0, "", make(chan ProgressUpdate, b.Problem.Size*2), nil, nil, 0, false, 0}
+	return &s
+}
+
+// Board returns the board the solver is working on.
+func (s *Solver) Board() *Board {
+	return s.b
+}
+
+// Initialized reports whether InitSolve has run.
+func (s *Solver) Initialized() bool {
+	return s.initialized
+}
+
+// Clone returns a solver working on a copy of s's board, without a progress
+// channel, trace or recording.
+func (s *Solver) Clone() *Solver {
+	return &Solver{s.b.Clone(), nil, s.ctx, s.initialized, s.rule, false, nil, s.steps, s.Action, nil, nil, nil, s.GuessWorkers, s.DeterministicGuesses, s.MaxSteps}
+}
+
+// Checkpoint takes a checkpoint of s's board, which Rollback also uses to
+// forget whether InitSolve has run since.
+func (s *Solver) Checkpoint() Checkpoint {
+	cp := s.b.Checkpoint()
+	initialized := s.initialized
+	s.b.trail.record(func() { s.initialized = initialized })
+	return cp
+}
+
+// Rollback returns s's board to cp.
+func (s *Solver) Rollback(cp Checkpoint) {
+	s.b.Rollback(cp)
+}
+
+// Cancelled reports whether the solver's context has been cancelled. Once it
+// has, partially enumerated possibilities can no longer be trusted, so no
+// further deductions should be made.
+func (s *Solver) Cancelled() bool {
+	return s.ctx.Err() != nil
+}
 
 func (s *Solver) UpdateAction(a string) {
 	s.Action = a
 	s.SendProgress()
 }
 
+// BeginRule records which rule is responsible for the marks that follow and
+// updates the displayed action.
+func (s *Solver) BeginRule(r Rule, a string) {
+	s.rule = r
+	s.UpdateAction(a)
+}
+
+// useRule is BeginRule for rules applied a cell or an island at a time,
+// which only updates the action when the rule changes.
+func (s *Solver) useRule(r Rule, a string) {
+	if s.rule != r {
+		s.BeginRule(r, a)
+	}
+}
+
 func (s *Solver) SendProgress() {
+	s.sendProgress(NilCoordinate(), UNKNOWN)
+}
+
+func (s *Solver) sendProgress(marked Coordinate, cell Cell) {
 	if s.Progress == nil {
 		return
 	}
-	s.Progress <- ProgressUpdate{
+	select {
+	case s.Progress <- ProgressUpdate{
 		s.Action,
 		s.b.TotalMarked,
 		s.b.Problem.Size,
+		marked,
+		cell,
+	}:
+	case <-s.ctx.Done():
 	}
 }
 
 func (s *Solver) MarkPainted(r int, c int) bool {
-	result := s.b.MarkPainted(r, c)
-	if result {
-		s.SendProgress()
-	}
-	return result
+	return s.MarkBecause(r, c, PAINTED, nil)
 }
 
 func (s *Solver) MarkClear(r int, c int) bool {
-	result := s.b.MarkClear(r, c)
-	if result {
-		s.SendProgress()
-	}
-	return result
+	return s.MarkBecause(r, c, CLEAR, nil)
 }
 
 func (s *Solver) Mark(r int, c int, cell Cell) bool {
-	result := s.b.Mark(r, c, cell)
-	s.SendProgress()
-	return result
+	return s.MarkBecause(r, c, cell, nil)
+}
+
+// MarkBecause marks a cell on behalf of the current rule; support holds the
+// cells that justify the mark and islands the islands involved. While
+// stepping through NextDeduction, only the first mark is made.
+func (s *Solver) MarkBecause(r int, c int, cell Cell, support *CoordinateSet, islands ...*Island) bool {
+	return s.MarkDeduction(Deduction{Rule: s.rule, Coord: Coordinate{r, c}, Cell: cell, Supporting: support}, islands...)
+}
+
+// MarkDeduction applies d to the board, recording it in the trace and
+// handing it to NextDeduction as needed.
+func (s *Solver) MarkDeduction(d Deduction, islands ...*Island) bool {
+	if s.stepping && s.next != nil {
+		return false
+	}
+	recording := s.stepping || s.Trace != nil
+	if recording {
+		//islands are merged by the mark, so describe them beforehand
+		d.Islands = make([]IslandRef, 0, len(islands))
+		for _, i := range islands {
+			d.Islands = append(d.Islands, i.Ref())
+		}
+	}
+	if !s.b.Mark(d.Coord.Row, d.Coord.Col, d.Cell) {
+		return false
+	}
+	if recording {
+		if d.Supporting == nil {
+			d.Supporting = EmptyCoordinateSet()
+		}
+		if s.stepping {
+			s.next = &d
+		}
+		if s.Trace != nil {
+			s.Trace.Add(d)
+		}
+	}
+	if s.Recording != nil {
+		s.Recording.Add(d.Coord, d.Cell, s.Action)
+	}
+	s.sendProgress(d.Coord, d.Cell)
+	return true
 }
 
 func (s *Solver) AddIslandBorders() bool {
-	s.UpdateAction("Adding island borders")
-	Watch.Start("AIB")
-	defer Watch.Stop("AIB")
+	s.BeginRule(RuleAddIslandBorders, "Adding island borders")
+	s.b.Watch.Start("AIB")
+	defer s.b.Watch.Stop("AIB")
 	didChange := false
 	for _, island := range s.b.Islands {
-		if island.ReadyForBorders {
-			targets := s.b.NeighborsWith(island.Members, UNKNOWN)
-			for coord := range targets.Map {
-				didChange = s.MarkPainted(coord.Row, coord.Col) || didChange
-			}
-			island.ReadyForBorders = false
-		}
+		didChange = s.addBordersTo(island) || didChange
+	}
+	return didChange
+}
+
+// addBordersTo paints the liberties of island if it is ready for borders.
+func (s *Solver) addBordersTo(island *Island) bool {
+	if !island.ReadyForBorders {
+		return false
+	}
+	didChange := false
+	complete := true
+	for _, coord := range island.Liberties.ToSlice() {
+		s.useRule(RuleAddIslandBorders, "Adding island borders")
+		marked := s.MarkBecause(coord.Row, coord.Col, PAINTED, island.Members, island)
+		didChange = marked || didChange
+		complete = complete && marked
+	}
+	if complete {
+		s.b.recordIsland(island)
+		island.ReadyForBorders = false
 	}
 	return didChange
 }
 
 // TODO: liberty data structure? running slices?
 func (s *Solver) ExtendIslandsOneLiberty() bool {
-	s.UpdateAction("Extending islands (1 liberty)")
-	Watch.Start("EI1")
-	defer Watch.Stop("EI1")
+	s.BeginRule(RuleExtendIslandsOneLiberty, "Extending islands (1 liberty)")
+	s.b.Watch.Start("EI1")
+	defer s.b.Watch.Stop("EI1")
 	didChange := false
-	changed := true
-	for changed {
-		changed = false
-		for _, island := range s.b.Islands {
-			if island.CurrentSize == island.TargetSize {
-				continue
-			}
-			lib := s.b.Liberties(island)
-			if lib.Size() == 1 {
-				c := lib.OneMember()
-				result := s.MarkClear(c.Row, c.Col)
-				didChange = didChange || result
-				changed = changed || result
+	for {
+		island := s.b.nextLibertyChange(&s.b.islandQueue)
+		if island == nil {
+			break
+		}
+		if island.CurrentSize != island.TargetSize && island.Liberties.Size() == 1 {
+			c := island.Liberties.OneMember()
+			if !s.MarkBecause(c.Row, c.Col, CLEAR, island.Members, island) {
 				break
 			}
+			didChange = true
+			continue
 		}
+		s.b.dropLibertyChange(&s.b.islandQueue)
 	}
 	return didChange
 }
 
-// TODO: liberty data structure? running slices?
 func (s *Solver) ExtendWallIslandsOneLiberty() bool {
-	s.UpdateAction("Extend wall islands (1 liberty)")
-	Watch.Start("EW1")
-	defer Watch.Stop("EW1")
+	s.BeginRule(RuleExtendWallIslandsOneLiberty, "Extend wall islands (1 liberty)")
+	s.b.Watch.Start("EW1")
+	defer s.b.Watch.Stop("EW1")
 	didChange := false
-	changed := true
-	for changed {
-		changed = false
-		for _, island := range s.b.WallIslands {
-			if len(s.b.WallIslands) == 1 {
-				break
-			}
-			if island.CurrentSize == s.b.Problem.TargetWallCount {
-				return didChange
-			}
-			lib := s.b.Liberties(island)
-			if lib.Size() == 1 {
-				c := lib.OneMember()
-				result := s.MarkPainted(c.Row, c.Col)
-				didChange = didChange || result
-				changed = changed || result
+	for len(s.b.WallIslands) > 1 {
+		island := s.b.nextLibertyChange(&s.b.wallQueue)
+		if island == nil || island.CurrentSize == s.b.Problem.TargetWallCount {
+			break
+		}
+		if island.Liberties.Size() == 1 {
+			c := island.Liberties.OneMember()
+			if !s.MarkBecause(c.Row, c.Col, PAINTED, island.Members, island) {
 				break
 			}
+			didChange = true
+			continue
 		}
+		s.b.dropLibertyChange(&s.b.wallQueue)
 	}
 	return didChange
 }
@@ -138,35 +269,40 @@ func (s *Solver) ExtendWallIslandsOneLiberty() bool {
 // have each island count their liberties and identify each cell that is a
 // liberty to two different islands?
 func (s *Solver) PaintTwoBorderedCells() bool {
-	s.UpdateAction("Two-bordered cells")
-	Watch.Start("P2B")
-	defer Watch.Stop("P2B")
+	s.BeginRule(RulePaintTwoBorderedCells, "Two-bordered cells")
+	s.b.Watch.Start("P2B")
+	defer s.b.Watch.Stop("P2B")
 	didChange := false
 	for ri, row := range s.b.Grid {
-		for ci, col := range row {
-			if col != UNKNOWN {
-				continue
-			}
-			borderCount := 0
-			for _, i := range s.b.Islands {
-				if i.TargetSize == 0 {
-					continue
-				}
-				if i.BordersCell(Coordinate{ri, ci}) {
-					borderCount++
-					if borderCount > 1 {
-						break
-					}
-				}
-			}
-			if borderCount > 1 {
-				didChange = s.MarkPainted(ri, ci) || didChange
-			}
+		for ci := range row {
+			didChange = s.paintIfTwoBordered(Coordinate{ri, ci}) || didChange
 		}
 	}
 	return didChange
 }
 
+// paintIfTwoBordered paints c if it is unknown and borders two different
+// numbered islands.
+func (s *Solver) paintIfTwoBordered(c Coordinate) bool {
+	if s.b.Get(c) != UNKNOWN {
+		return false
+	}
+	bordering := make([]*Island, 0, 2)
+	for _, n := range []Coordinate{c.Translate(-1, 0), c.Translate(1, 0), c.Translate(0, -1), c.Translate(0, 1)} {
+		i := s.b.IslandAt(n.Row, n.Col)
+		if i == nil || i.TargetSize == 0 || (len(bordering) > 0 && bordering[0] == i) {
+			continue
+		}
+		bordering = append(bordering, i)
+		if len(bordering) > 1 {
+			s.useRule(RulePaintTwoBorderedCells, "Two-bordered cells")
+			borders := bordering[0].Members.Plus(bordering[1].Members)
+			return s.MarkBecause(c.Row, c.Col, PAINTED, borders, bordering...)
+		}
+	}
+	return false
+}
+
 func (s *Solver) WallDfs(members *CoordinateSet) *CoordinateSet {
 	necessary := EmptyCoordinateSet()
 	for r := 0; r < s.b.Problem.Height; r++ {
@@ -184,7 +320,7 @@ func (s *Solver) WallDfsRec(members *CoordinateSet, necessary *CoordinateSet) {
 		return
 	}
 	if s.b.HasNeighborWith(members, PAINTED) {
-		for nCheck := range necessary.Map {
+		for _, nCheck := range necessary.ToSlice() {
 			if !members.Contains(nCheck) {
 				necessary.Del(nCheck)
 			}
@@ -195,7 +331,7 @@ func (s *Solver) WallDfsRec(members *CoordinateSet, necessary *CoordinateSet) {
 		return
 	}
 	neighbors := s.b.NeighborsWith(members, UNKNOWN)
-	for n := range neighbors.Map {
+	for _, n := range neighbors.ToSlice() {
 		if s.b.Get(n) == UNKNOWN && members.CanAddWall(n) {
 			members.Add(n)
 			s.WallDfsRec(members, necessary)
@@ -209,9 +345,9 @@ func (s *Solver) WallDfsRec(members *CoordinateSet, necessary *CoordinateSet) {
 // 2. Do it all in one recursive function, passing necessary through and dumping the channel; skip any possibility
 // with necessary as a subset of the possibility
 func (s *Solver) ExtendWallIslands() bool {
-	s.UpdateAction("Extend wall islands")
-	Watch.Start("EWI")
-	defer Watch.Stop("EWI")
+	s.BeginRule(RuleExtendWallIslands, "Extend wall islands")
+	s.b.Watch.Start("EWI")
+	defer s.b.Watch.Stop("EWI")
 	if len(s.b.WallIslands) < 2 {
 		return false
 	}
@@ -221,48 +357,60 @@ func (s *Solver) ExtendWallIslands() bool {
 			return didChange
 		}
 		necessaryMembers := s.WallDfs(wi.Members)
-		for target := range necessaryMembers.Map {
-			didChange = s.MarkPainted(target.Row, target.Col) || didChange
+		for _, target := range necessaryMembers.ToSlice() {
+			didChange = s.MarkBecause(target.Row, target.Col, PAINTED, wi.Members, wi) || didChange
 		}
 	}
 	return didChange
 }
 
 func (s *Solver) FillElbows() bool {
-	s.UpdateAction("Fill elbows")
-	Watch.Start("FEL")
-	defer Watch.Stop("FEL")
+	s.BeginRule(RuleFillElbows, "Fill elbows")
+	s.b.Watch.Start("FEL")
+	defer s.b.Watch.Stop("FEL")
 	//TODO: make more efficient with overlapping columns that we save between inner loop iterations?
 	didChange := false
 	for r := 0; r < s.b.Problem.Height-1; r++ {
 		for c := 0; c < s.b.Problem.Width-1; c++ {
-			painted := 0
-			clear := 0
-			target := Coordinate{}
-			for dr := 0; dr < 2; dr++ {
-				for dc := 0; dc < 2; dc++ {
-					switch s.b.Grid[r+dr][c+dc] {
-					case PAINTED:
-						painted++
-					case CLEAR:
-						clear++
-					case UNKNOWN:
-						target = Coordinate{r + dr, c + dc}
-					}
-				}
-			}
-			if painted == 3 && clear != 1 {
-				didChange = s.MarkClear(target.Row, target.Col) || didChange
-			}
+			didChange = s.fillElbowAt(r, c) || didChange
 		}
 	}
 	return didChange
 }
 
-func Check(b *Board, soln *Board) {
-	abort := false
+// fillElbowAt clears the last cell of the 2x2 square with its top left
+// corner at (r, c) if the other three are painted.
+func (s *Solver) fillElbowAt(r int, c int) bool {
+	painted := 0
+	clear := 0
+	target := Coordinate{}
+	walls := EmptyCoordinateSetSz(r + 2)
+	for dr := 0; dr < 2; dr++ {
+		for dc := 0; dc < 2; dc++ {
+			switch s.b.Grid[r+dr][c+dc] {
+			case PAINTED:
+				painted++
+				walls.Add(Coordinate{r + dr, c + dc})
+			case CLEAR:
+				clear++
+			case UNKNOWN:
+				target = Coordinate{r + dr, c + dc}
+			}
+		}
+	}
+	if painted == 3 && clear != 1 {
+		s.useRule(RuleFillElbows, "Fill elbows")
+		return s.MarkBecause(target.Row, target.Col, CLEAR, walls)
+	}
+	return false
+}
+
+// Check compares b against a known solution and returns an error describing
+// every marked cell or island that disagrees with it.
+func Check(b *Board, soln *Board) error {
+	mistakes := make([]string, 0)
 	if soln == nil {
-		return
+		return nil
 	}
 	for r := 0; r < b.Problem.Height; r++ {
 		for c := 0; c < b.Problem.Width; c++ {
@@ -270,13 +418,19 @@ func Check(b *Board, soln *Board) {
 				continue
 			}
 			if b.Grid[r][c] != soln.Grid[r][c] {
-				fmt.Printf("ERROR AT %v, %v\n%v\n", r, c, b)
-				abort = true
+				mistakes = append(mistakes, fmt.Sprintf("cell %v is wrong", Coordinate{r, c}))
 			}
 		}
 	}
 	for _, si := range soln.Islands {
+		if !si.IsRooted() {
+			continue
+		}
 		myI := b.IslandAt(si.Root.Row, si.Root.Col)
+		if myI == nil {
+			mistakes = append(mistakes, fmt.Sprintf("no island at %v (correct is %v)", si.Root, si))
+			continue
+		}
 		found := false
 		if myI.Members.Equals(si.Members) {
 			found = true
@@ -288,32 +442,54 @@ func Check(b *Board, soln *Board) {
 			}
 		}
 		if !found {
-			fmt.Printf("ERROR on island %v (correct is %v)\n", myI, si)
-			for _, p := range myI.Possibilities {
-				fmt.Printf("Poss %v\n", p)
-			}
-			abort = true
-
+			mistakes = append(mistakes, fmt.Sprintf("island %v has no correct possibility (correct is %v)", myI, si))
 		}
 	}
-	if abort {
-		os.Exit(0)
+	if len(mistakes) > 0 {
+		return fmt.Errorf("%s", strings.Join(mistakes, "; "))
 	}
+	return nil
 }
 
 func (s *Solver) FalsifyGuess(r int, c int, cell Cell, skipExpensive bool) error {
-	hypo := Solver{s.b.Clone(), nil, s.Action, nil}
+	return s.falsifyGuess(s.ctx, r, c, cell, skipExpensive)
+}
+
+// falsifyGuess is FalsifyGuess with the hypothesis solved under ctx. If ctx
+// is done before the hypothesis is finished, the result means nothing. The
+// hypothesis is solved on s's own board, which is rolled back afterwards.
+func (s *Solver) falsifyGuess(ctx context.Context, r int, c int, cell Cell, skipExpensive bool) error {
+	cp := s.b.Checkpoint()
+	defer s.b.Rollback(cp)
+	hypo := Solver{s.b, nil, ctx, true, s.rule, false, nil, 0, s.Action, nil, nil, nil, 0, false, 0}
 	hypo.b.Mark(r, c, cell)
 	hypo.AutoSolve(false, skipExpensive)
 	return hypo.b.ContainsError()
 }
 
-func (s *Solver) MakeAGuess(neighborsOnly bool, skipExpensive bool) bool {
-	if neighborsOnly {
-		s.UpdateAction("Make a guess (island neighbors)")
-	} else {
-		s.UpdateAction("Make a guess (non island neighbors)")
+// testGuess clears and then paints c. If either leads to a contradiction, it
+// returns the colour c must have instead and the contradiction; otherwise it
+// returns UNKNOWN and nil, as it does if ctx is done first.
+func (s *Solver) testGuess(ctx context.Context, c Coordinate, skipExpensive bool) (Cell, error) {
+	for _, guess := range []Cell{CLEAR, PAINTED} {
+		e := s.falsifyGuess(ctx, c.Row, c.Col, guess, skipExpensive)
+		if ctx.Err() != nil {
+			return UNKNOWN, nil
+		}
+		if e != nil {
+			if guess == CLEAR {
+				return PAINTED, e
+			}
+			return CLEAR, e
+		}
 	}
+	return UNKNOWN, nil
+}
+
+// guessCandidates lists the cells MakeAGuess tries, in the order it tries
+// them.
+func (s *Solver) guessCandidates(neighborsOnly bool) []Coordinate {
+	out := make([]Coordinate, 0)
 	for r := 0; r < s.b.Problem.Height; r++ {
 		for c := 0; c < s.b.Problem.Width; c++ {
 			if s.b.Grid[r][c] != UNKNOWN {
@@ -322,66 +498,222 @@ func (s *Solver) MakeAGuess(neighborsOnly bool, skipExpensive bool) bool {
 			if neighborsOnly && !s.b.HasNeighborWith(SingleCoordinateSet(Coordinate{r, c}), CLEAR) {
 				continue
 			}
-			e := s.FalsifyGuess(r, c, CLEAR, skipExpensive)
-			if e != nil {
-				s.MarkPainted(r, c)
-				return true
+			out = append(out, Coordinate{r, c})
+		}
+	}
+	return out
+}
+
+func (s *Solver) MakeAGuess(neighborsOnly bool, skipExpensive bool) bool {
+	rule := RuleDeepGuess
+	if skipExpensive {
+		rule = RuleShallowGuess
+	}
+	if neighborsOnly {
+		s.BeginRule(rule, "Make a guess (island neighbors)")
+	} else {
+		s.BeginRule(rule, "Make a guess (non island neighbors)")
+	}
+	candidates := s.guessCandidates(neighborsOnly)
+	idx, cell, e := -1, Cell(UNKNOWN), error(nil)
+	if s.GuessWorkers > 1 {
+		idx, cell, e = s.parallelGuess(candidates, skipExpensive)
+	} else {
+		for i, c := range candidates {
+			if s.Cancelled() || !s.step() {
+				return false
 			}
-			e = s.FalsifyGuess(r, c, PAINTED, skipExpensive)
-			if e != nil {
-				s.MarkClear(r, c)
-				return true
+			if cell, e = s.testGuess(s.ctx, c, skipExpensive); e != nil {
+				idx = i
+				break
 			}
 		}
 	}
-	return false
+	if s.Cancelled() || idx < 0 {
+		return false
+	}
+	s.MarkDeduction(Deduction{Rule: s.rule, Coord: candidates[idx], Cell: cell, Contradiction: e.Error()})
+	return true
+}
+
+type guessJob struct {
+	idx int
+	ctx context.Context
+}
+
+type guessResult struct {
+	idx  int
+	cell Cell
+	err  error
+}
+
+// parallelGuess tests candidates with a pool of GuessWorkers goroutines, each
+// with its own copy of the board, and returns the index of a cell that leads
+// to a contradiction, the colour it must have and the contradiction, or -1 if
+// none does. Once a contradiction
+// is found no more cells are started and the cells still being tested are
+// cancelled; with DeterministicGuesses, only the cells after it are
+// cancelled and the earliest contradiction wins, as in the sequential scan.
+func (s *Solver) parallelGuess(candidates []Coordinate, skipExpensive bool) (int, Cell, error) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+	jobs := make(chan guessJob)
+	results := make(chan guessResult)
+	workers := s.GuessWorkers
+	if workers > len(candidates) {
+		workers = len(candidates)
+	}
+	probes := make([]*Solver, workers)
+	for i := range probes {
+		probes[i] = s.Clone()
+	}
+	var wg sync.WaitGroup
+	for _, probe := range probes {
+		wg.Add(1)
+		go func(probe *Solver) {
+			defer wg.Done()
+			for job := range jobs {
+				cell, e := probe.testGuess(job.ctx, candidates[job.idx], skipExpensive)
+				results <- guessResult{job.idx, cell, e}
+			}
+		}(probe)
+	}
+
+	best := guessResult{-1, UNKNOWN, nil}
+	cancels := make([]context.CancelFunc, 0, len(candidates))
+	var next guessJob
+	pending := 0
+	for {
+		var send chan guessJob
+		if best.idx < 0 && len(cancels) < len(candidates) && !s.Cancelled() {
+			if next.ctx == nil && s.step() {
+				var jobCancel context.CancelFunc
+				next.idx = len(cancels)
+				next.ctx, jobCancel = context.WithCancel(ctx)
+				cancels = append(cancels, jobCancel)
+			}
+			if next.ctx != nil {
+				send = jobs
+			}
+		}
+		if send == nil && pending == 0 {
+			break
+		}
+		select {
+		case send <- next:
+			next = guessJob{}
+			pending++
+		case r := <-results:
+			pending--
+			if r.err == nil || (best.idx >= 0 && r.idx > best.idx) {
+				continue
+			}
+			best = r
+			if !s.DeterministicGuesses {
+				cancel()
+				continue
+			}
+			for i := best.idx + 1; i < len(cancels); i++ {
+				cancels[i]()
+			}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	for _, probe := range probes {
+		s.b.Watch.Add(probe.b.Watch)
+	}
+	return best.idx, best.cell, best.err
 }
 
 func (s *Solver) InitSolve() {
-	s.UpdateAction("Initialize solve")
+	s.BeginRule(RuleInitialize, "Initialize solve")
 	s.PaintTwoBorderedCells()
 	s.ExtendIslandsOneLiberty()
 	s.AddIslandBorders()
 	s.PopulateIslandPossibilities()
+	s.initialized = true
 }
 
-func (s *Solver) AutoSolve(makeGuesses bool, skipExpensive bool) bool {
-	Watch.Start("AutoSolve")
-	changed := true
-	for changed {
-		changed = false
-		changed = changed || s.PaintTwoBorderedCells()
-		changed = changed || s.ExtendIslandsOneLiberty()
-		changed = changed || s.AddIslandBorders()
-		changed = changed || s.PaintUnreachables()
-		s.UpdateAction("Stripping possibilities")
-		changed = changed || s.b.StripAllPossibilities()
-		changed = changed || s.ExtendWallIslandsOneLiberty()
-		changed = changed || s.ConnectUnrootedIslands()
-		changed = changed || s.FindSinglePoolPreventers()
-		changed = changed || s.FillIslandNecessaries()
-		changed = changed || s.AddIslandBorders()
-		changed = changed || s.FillElbows()
-		changed = changed || s.AddIslandBorders()
-		changed = changed || s.ExtendWallIslands()
+func (s *Solver) StripAllPossibilities() bool {
+	s.BeginRule(RuleStripPossibilities, "Stripping possibilities")
+	return s.b.StripAllPossibilities()
+}
 
+// solveStep runs the rules in order until one of them changes the board and
+// reports whether any did. The expensive rules and guesses are only tried
+// once the board is known to be unfinished and free of errors.
+func (s *Solver) solveStep(makeGuesses bool, skipExpensive bool) bool {
+	rules := []func() bool{
+		s.PaintTwoBorderedCells,
+		s.ExtendIslandsOneLiberty,
+		s.AddIslandBorders,
+		s.PaintUnreachables,
+		s.StripAllPossibilities,
+		s.ExtendWallIslandsOneLiberty,
+		s.ConnectUnrootedIslands,
+		s.FindSinglePoolPreventers,
+		s.FillIslandNecessaries,
+		s.AddIslandBorders,
+		s.FillElbows,
+		s.AddIslandBorders,
+		s.ExtendWallIslands,
+	}
+	for _, r := range rules {
+		if r() {
+			return true
+		}
+	}
+	if s.b.TotalMarked == s.b.Problem.Size {
+		return false
+	}
+	if err := s.b.ContainsError(); err != nil {
+		return false
+	}
+	if s.Cancelled() {
+		return false
+	}
+	if !skipExpensive {
+		if s.EliminateIntolerables() || s.EliminateWallSplitters() {
+			return true
+		}
+	}
+	if makeGuesses {
+		return s.MakeAGuess(true, true) ||
+			s.MakeAGuess(false, true) ||
+			s.MakeAGuess(true, skipExpensive || false) ||
+			s.MakeAGuess(false, skipExpensive || false)
+	}
+	return false
+}
+
+// step counts one step against MaxSteps, reporting false instead if the
+// budget is spent.
+func (s *Solver) step() bool {
+	if s.MaxSteps > 0 && s.steps >= s.MaxSteps {
+		return false
+	}
+	s.steps++
+	return true
+}
+
+func (s *Solver) AutoSolve(makeGuesses bool, skipExpensive bool) bool {
+	s.b.Watch.Start("AutoSolve")
+	for !s.Cancelled() {
+		s.propagate()
 		if s.b.TotalMarked == s.b.Problem.Size {
 			break
 		}
 		if err := s.b.ContainsError(); err != nil {
 			break
 		}
-		if !skipExpensive {
-			changed = changed || s.EliminateIntolerables()
-			changed = changed || s.EliminateWallSplitters()
+		if !s.step() {
+			break
 		}
-		if makeGuesses {
-			changed = changed || s.MakeAGuess(true, true)
-			changed = changed || s.MakeAGuess(false, true)
-			changed = changed || s.MakeAGuess(true, skipExpensive || false)
-			changed = changed || s.MakeAGuess(false, skipExpensive || false)
+		if !s.solveStep(makeGuesses, skipExpensive) {
+			break
 		}
 	}
-	Watch.Stop("AutoSolve")
+	s.b.Watch.Stop("AutoSolve")
 	return true
 }
@@ -5,21 +5,25 @@ import (
 	"time"
 )
 
-var Watch Stopwatch
-
+// Stopwatch accumulates the time spent in named buckets. Each board has its
+// own, and so does each clone, since concurrent work on one Stopwatch would
+// overwrite its start times; Add folds a clone's buckets back in. A nil
+// Stopwatch ignores every call.
 type Stopwatch struct {
 	Buckets      map[string]int64
 	BucketStarts map[string]int64
 }
 
-func init() {
-	Watch = Stopwatch{}
-	Watch.Buckets = make(map[string]int64)
-	Watch.BucketStarts = make(map[string]int64)
-	Watch.Start("")
+func NewStopwatch() *Stopwatch {
+	s := &Stopwatch{Buckets: make(map[string]int64), BucketStarts: make(map[string]int64)}
+	s.Start("")
+	return s
 }
 
 func (s *Stopwatch) Start(b string) {
+	if s == nil {
+		return
+	}
 	s.BucketStarts[b] = time.Now().UnixNano()
 	_, ok := s.Buckets[b]
 	if !ok {
@@ -28,6 +32,9 @@ func (s *Stopwatch) Start(b string) {
 }
 
 func (s *Stopwatch) Stop(b string) {
+	if s == nil {
+		return
+	}
 	end := time.Now().UnixNano()
 	start, ok := s.BucketStarts[b]
 	if !ok {
@@ -38,16 +45,36 @@ func (s *Stopwatch) Stop(b string) {
 }
 
 func (s *Stopwatch) Results() string {
+	if s == nil {
+		return ""
+	}
+	s.Stop("")
 	out := ""
 	for k, v := range s.Buckets {
 		out += fmt.Sprintf("%s: %.4f\n", k, float64(v)/1000000000.0)
 	}
-	s.Stop("")
 	out += fmt.Sprintf("TOTAL: %.4f\n", float64(s.Buckets[""])/1000000000.0)
 	s.Start("")
 	return out
 }
 
 func (s *Stopwatch) BucketNanos(b string) int64 {
+	if s == nil {
+		return 0
+	}
 	return s.Buckets[b]
 }
+
+// Add adds the buckets of o, a stopwatch that has stopped running, to s. The
+// total is left alone: o's work happened while s's total was running, and
+// work done by several clones at once can add up to more than it.
+func (s *Stopwatch) Add(o *Stopwatch) {
+	if s == nil || o == nil {
+		return
+	}
+	for k, v := range o.Buckets {
+		if k != "" {
+			s.Buckets[k] += v
+		}
+	}
+}
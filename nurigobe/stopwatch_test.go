@@ -0,0 +1,18 @@
+package nurigobe
+
+import "testing"
+
+func TestCloneStopwatch(t *testing.T) {
+	b := readProblem(t, "problem1.txt")
+	c := b.Clone()
+	if c.Watch == b.Watch {
+		t.Fatal("clone shares its parent's stopwatch")
+	}
+	c.Watch.Start("work")
+	c.Watch.Stop("work")
+	before := b.Watch.BucketNanos("work")
+	b.Watch.Add(c.Watch)
+	if got, want := b.Watch.BucketNanos("work"), before+c.Watch.BucketNanos("work"); got != want {
+		t.Fatalf("got %d ns in the parent's bucket, want %d", got, want)
+	}
+}
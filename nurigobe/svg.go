@@ -0,0 +1,76 @@
+package nurigobe
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// SVGOptions controls the output of Board.WriteSVG.
+type SVGOptions struct {
+	// CellSize is the width and height of each cell in pixels.
+	CellSize int
+	// Highlight is a set of cells to shade, such as an island possibility
+	// or a hint, or nil.
+	Highlight      *CoordinateSet
+	HighlightColor string
+	WallColor      string
+}
+
+func DefaultSVGOptions() SVGOptions {
+	return SVGOptions{32, nil, "#ffd54f", "#333333"}
+}
+
+// WriteSVG draws the board as an SVG image: the grid, clue numbers, painted
+// cells and a dot in each clear cell without a clue.
+func (b *Board) WriteSVG(w io.Writer, opts SVGOptions) error {
+	_, err := io.WriteString(w, b.SVG(opts))
+	return err
+}
+
+func (b *Board) SVG(opts SVGOptions) string {
+	cs := opts.CellSize
+	if cs < 1 {
+		cs = DefaultSVGOptions().CellSize
+	}
+	width := b.Problem.Width * cs
+	height := b.Problem.Height * cs
+	clues := make(map[Coordinate]int, len(b.Problem.IslandSpecs))
+	for _, spec := range b.Problem.IslandSpecs {
+		clues[Coordinate{spec.Row, spec.Col}] = spec.Size
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"-1 -1 %d %d\">\n", width+2, height+2, width+2, height+2))
+	sb.WriteString(fmt.Sprintf("<rect x=\"0\" y=\"0\" width=\"%d\" height=\"%d\" fill=\"#ffffff\"/>\n", width, height))
+	for r := 0; r < b.Problem.Height; r++ {
+		for c := 0; c < b.Problem.Width; c++ {
+			x, y := c*cs, r*cs
+			coord := Coordinate{r, c}
+			if opts.Highlight != nil && opts.Highlight.Contains(coord) {
+				sb.WriteString(fmt.Sprintf("<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"%s\"/>\n", x, y, cs, cs, opts.HighlightColor))
+			}
+			if b.Grid[r][c] == PAINTED {
+				inset := 0
+				if opts.Highlight != nil && opts.Highlight.Contains(coord) {
+					inset = cs / 8
+				}
+				sb.WriteString(fmt.Sprintf("<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"%s\"/>\n", x+inset, y+inset, cs-2*inset, cs-2*inset, opts.WallColor))
+			}
+			if size, ok := clues[coord]; ok {
+				sb.WriteString(fmt.Sprintf("<text x=\"%d\" y=\"%d\" font-family=\"sans-serif\" font-size=\"%d\" text-anchor=\"middle\" dominant-baseline=\"central\">%d</text>\n", x+cs/2, y+cs/2, cs*3/5, size))
+			} else if b.Grid[r][c] == CLEAR {
+				sb.WriteString(fmt.Sprintf("<circle cx=\"%d\" cy=\"%d\" r=\"%d\" fill=\"%s\"/>\n", x+cs/2, y+cs/2, cs/10+1, opts.WallColor))
+			}
+		}
+	}
+	for r := 1; r < b.Problem.Height; r++ {
+		sb.WriteString(fmt.Sprintf("<line x1=\"0\" y1=\"%d\" x2=\"%d\" y2=\"%d\" stroke=\"#999999\" stroke-width=\"1\"/>\n", r*cs, width, r*cs))
+	}
+	for c := 1; c < b.Problem.Width; c++ {
+		sb.WriteString(fmt.Sprintf("<line x1=\"%d\" y1=\"0\" x2=\"%d\" y2=\"%d\" stroke=\"#999999\" stroke-width=\"1\"/>\n", c*cs, c*cs, height))
+	}
+	sb.WriteString(fmt.Sprintf("<rect x=\"0\" y=\"0\" width=\"%d\" height=\"%d\" fill=\"none\" stroke=\"#000000\" stroke-width=\"2\"/>\n", width, height))
+	sb.WriteString("</svg>\n")
+	return sb.String()
+}
@@ -0,0 +1,183 @@
+package nurigobe
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// IslandRef describes an island as it was when a deduction was made. Islands
+// are merged as cells are marked, so deductions keep these snapshots rather
+// than pointers.
+type IslandRef struct {
+	Wall bool
+	// Root is the numbered cell, or NilCoordinate() for unrooted and wall
+	// islands.
+	Root Coordinate
+	// Anchor is the island's top-left-most member.
+	Anchor     Coordinate
+	TargetSize int
+	Size       int
+}
+
+func (i *Island) Ref() IslandRef {
+	return IslandRef{i.IslandType == WALL_ISLAND, i.Root, i.Members.First(), i.TargetSize, i.CurrentSize}
+}
+
+func (r IslandRef) String() string {
+	if r.Wall {
+		return fmt.Sprintf("wall@(r%d,c%d)", r.Anchor.Row, r.Anchor.Col)
+	}
+	if r.Root.IsNil() {
+		return fmt.Sprintf("?@(r%d,c%d)", r.Anchor.Row, r.Anchor.Col)
+	}
+	return fmt.Sprintf("%d@(r%d,c%d)", r.TargetSize, r.Root.Row, r.Root.Col)
+}
+
+func joinIslandRefs(refs []IslandRef) string {
+	names := make([]string, 0, len(refs))
+	for _, r := range refs {
+		names = append(names, r.String())
+	}
+	if len(names) < 2 {
+		return strings.Join(names, "")
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+}
+
+func cellName(cell Cell) string {
+	switch cell {
+	case PAINTED:
+		return "painted"
+	case CLEAR:
+		return "clear"
+	}
+	return "unknown"
+}
+
+// Explanation describes the deduction in words, e.g.
+// "r3c5 painted: bordered by islands 4@(r2,c5) and 2@(r4,c4)".
+func (d *Deduction) Explanation() string {
+	if d.IsPruning() {
+		return fmt.Sprintf("pruned %d possibilities from %s", d.Pruned, joinIslandRefs(d.Islands))
+	}
+	islands := joinIslandRefs(d.Islands)
+	var why string
+	switch d.Rule {
+	case RulePaintTwoBorderedCells:
+		why = "bordered by islands " + islands
+	case RuleAddIslandBorders:
+		why = "borders complete island " + islands
+	case RuleExtendIslandsOneLiberty:
+		why = "only liberty of island " + islands
+	case RuleExtendWallIslandsOneLiberty:
+		why = "only liberty of " + islands
+	case RuleExtendWallIslands:
+		why = "needed to connect " + islands + " to the other walls"
+	case RuleFillElbows:
+		why = "would complete a pool with " + d.Supporting.SortedString()
+	case RulePaintUnreachables:
+		why = "no island can reach it"
+	case RuleFillIslandNecessaries:
+		if d.Cell == CLEAR {
+			why = "part of every possible shape of island " + islands
+		} else {
+			why = "borders every possible shape of island " + islands
+		}
+	case RuleShallowGuess, RuleDeepGuess:
+		opposite := Cell(PAINTED)
+		if d.Cell == PAINTED {
+			opposite = CLEAR
+		}
+		why = fmt.Sprintf("assuming %s leads to a contradiction (%s)", cellName(opposite), d.Contradiction)
+	default:
+		why = "deduced by " + string(d.Rule)
+	}
+	return fmt.Sprintf("r%dc%d %s: %s", d.Coord.Row, d.Coord.Col, cellName(d.Cell), why)
+}
+
+func (d *Deduction) String() string {
+	return d.Explanation()
+}
+
+// Trace is the ordered list of every cell marked during a solve.
+type Trace struct {
+	Steps []Deduction
+}
+
+func NewTrace() *Trace {
+	return &Trace{make([]Deduction, 0)}
+}
+
+func (t *Trace) Add(d Deduction) {
+	t.Steps = append(t.Steps, d)
+}
+
+func (t *Trace) String() string {
+	var sb strings.Builder
+	for idx, d := range t.Steps {
+		sb.WriteString(fmt.Sprintf("%d. %s [%s]\n", idx+1, d.Explanation(), d.Rule))
+	}
+	return sb.String()
+}
+
+type traceCoordJSON struct {
+	Row int `json:"row"`
+	Col int `json:"col"`
+}
+
+type traceIslandJSON struct {
+	Wall       bool            `json:"wall,omitempty"`
+	Root       *traceCoordJSON `json:"root,omitempty"`
+	Anchor     traceCoordJSON  `json:"anchor"`
+	TargetSize int             `json:"targetSize,omitempty"`
+	Size       int             `json:"size"`
+}
+
+type traceStepJSON struct {
+	Row           int               `json:"row"`
+	Col           int               `json:"col"`
+	Cell          string            `json:"cell"`
+	Rule          Rule              `json:"rule"`
+	Islands       []traceIslandJSON `json:"islands,omitempty"`
+	Supporting    []traceCoordJSON  `json:"supporting,omitempty"`
+	Contradiction string            `json:"contradiction,omitempty"`
+	Text          string            `json:"text"`
+}
+
+func (d Deduction) toJSON() traceStepJSON {
+	step := traceStepJSON{
+		Row:           d.Coord.Row,
+		Col:           d.Coord.Col,
+		Cell:          cellName(d.Cell),
+		Rule:          d.Rule,
+		Contradiction: d.Contradiction,
+		Text:          d.Explanation(),
+	}
+	for _, r := range d.Islands {
+		ij := traceIslandJSON{r.Wall, nil, traceCoordJSON{r.Anchor.Row, r.Anchor.Col}, r.TargetSize, r.Size}
+		if !r.Root.IsNil() {
+			ij.Root = &traceCoordJSON{r.Root.Row, r.Root.Col}
+		}
+		step.Islands = append(step.Islands, ij)
+	}
+	if d.Supporting != nil {
+		for _, c := range d.Supporting.ToSlice() {
+			step.Supporting = append(step.Supporting, traceCoordJSON{c.Row, c.Col})
+		}
+	}
+	return step
+}
+
+// MarshalJSON writes the deduction in the same form as a step of a Trace.
+func (d Deduction) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.toJSON())
+}
+
+func (t *Trace) MarshalJSON() ([]byte, error) {
+	steps := make([]traceStepJSON, 0, len(t.Steps))
+	for _, d := range t.Steps {
+		steps = append(steps, d.toJSON())
+	}
+	return json.Marshal(steps)
+}
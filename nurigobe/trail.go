@@ -0,0 +1,104 @@
+package nurigobe
+
+// Checkpoint is a point in a board's history that Rollback can return it to.
+// It stays valid until it, or a checkpoint taken before it, is rolled back.
+type Checkpoint int
+
+// trail records how to undo each change made to a board while it holds a
+// checkpoint.
+type trail struct {
+	undo []func()
+	// checkpoints holds the length of undo when each checkpoint still held
+	// was taken; a Checkpoint is an index into it.
+	checkpoints []int
+}
+
+func (t *trail) recording() bool {
+	return len(t.checkpoints) > 0
+}
+
+// record adds undo to the trail if a checkpoint is held.
+func (t *trail) record(undo func()) {
+	if t.recording() {
+		t.undo = append(t.undo, undo)
+	}
+}
+
+// Checkpoint starts recording the changes made to b and returns the point to
+// pass to Rollback. Changes made through the Board's methods and the
+// solver's rules are recorded; changes made directly to its fields are not.
+func (b *Board) Checkpoint() Checkpoint {
+	b.trail.checkpoints = append(b.trail.checkpoints, len(b.trail.undo))
+	return Checkpoint(len(b.trail.checkpoints) - 1)
+}
+
+// Rollback undoes every change made to b since cp was returned by
+// Checkpoint, and forgets cp and the checkpoints taken after it; the ones
+// taken before it are still held. Once no checkpoints are left, changes are
+// no longer recorded.
+func (b *Board) Rollback(cp Checkpoint) {
+	if cp < 0 || int(cp) >= len(b.trail.checkpoints) {
+		return
+	}
+	for len(b.trail.undo) > b.trail.checkpoints[cp] {
+		last := len(b.trail.undo) - 1
+		b.trail.undo[last]()
+		b.trail.undo[last] = nil
+		b.trail.undo = b.trail.undo[:last]
+	}
+	b.trail.checkpoints = b.trail.checkpoints[:cp]
+}
+
+// recordCell records the cell at (r, c) and the marked count before the
+// cell is marked.
+func (b *Board) recordCell(r int, c int) {
+	if !b.trail.recording() {
+		return
+	}
+	cell, marked := b.Grid[r][c], b.TotalMarked
+	b.trail.record(func() {
+		b.Grid[r][c] = cell
+		b.TotalMarked = marked
+	})
+}
+
+// recordIsland records i's fields, to be restored on rollback, before they
+// are changed.
+func (b *Board) recordIsland(i *Island) {
+	if !b.trail.recording() {
+		return
+	}
+	saved := *i
+	b.trail.record(func() { *i = saved })
+}
+
+// recordSet records the members of cs before it is changed in place.
+func (b *Board) recordSet(cs *CoordinateSet) {
+	if !b.trail.recording() {
+		return
+	}
+	saved := cs.Copy()
+	b.trail.record(func() { *cs = *saved })
+}
+
+// setPossibilities replaces i's possibilities.
+func (b *Board) setPossibilities(i *Island, possibilities []*CoordinateSet) {
+	b.recordIsland(i)
+	i.Possibilities = possibilities
+	b.addReachers(i, possibilities)
+	b.queuePossibilities(i)
+}
+
+// removePossibility removes i's possibility idx, moving the last one into
+// its place.
+func (b *Board) removePossibility(i *Island, idx int) {
+	if b.trail.recording() {
+		saved, possibilities, removed := *i, i.Possibilities, i.Possibilities[idx]
+		b.trail.record(func() {
+			possibilities[idx] = removed
+			*i = saved
+		})
+	}
+	RemoveFromSlice(&i.Possibilities, idx)
+	b.queuePossibilities(i)
+}
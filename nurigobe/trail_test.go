@@ -0,0 +1,77 @@
+package nurigobe
+
+import "testing"
+
+const trailProblem = `______
+__5___
+_1_2__
+_____4
+5_____
+__2_1_
+_____3
+______`
+
+func trailBoard(t *testing.T) *Board {
+	b, err := BoardFromString(trailProblem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestRollbackNested(t *testing.T) {
+	b := trailBoard(t)
+	marked := b.TotalMarked
+	outer := b.Checkpoint()
+	b.MarkPainted(0, 0)
+	inner := b.Checkpoint()
+	b.MarkClear(0, 1)
+	b.Rollback(inner)
+	if b.Grid[0][1] != UNKNOWN || b.Grid[0][0] != PAINTED || b.TotalMarked != marked+1 {
+		t.Fatalf("inner rollback: got %v, %d marked", b, b.TotalMarked)
+	}
+	b.MarkPainted(1, 0)
+	b.Rollback(outer)
+	if b.Grid[0][0] != UNKNOWN || b.Grid[1][0] != UNKNOWN || b.TotalMarked != marked {
+		t.Fatalf("outer rollback: got %v, %d marked", b, b.TotalMarked)
+	}
+	if b.trail.recording() {
+		t.Fatalf("still recording after rolling back the last checkpoint")
+	}
+}
+
+func TestRollbackEqualLength(t *testing.T) {
+	b := trailBoard(t)
+	marked := b.TotalMarked
+	cp1 := b.Checkpoint()
+	cp2 := b.Checkpoint()
+	b.Rollback(cp2)
+	b.MarkPainted(0, 0)
+	b.Rollback(cp1)
+	if b.Grid[0][0] != UNKNOWN || b.TotalMarked != marked {
+		t.Fatalf("got %v, %d marked", b, b.TotalMarked)
+	}
+}
+
+func TestRollbackSolver(t *testing.T) {
+	b := trailBoard(t)
+	s := NewSolver(b)
+	s.Progress = nil
+	before := b.String()
+	outer := s.Checkpoint()
+	inner := s.Checkpoint()
+	s.InitSolve()
+	s.AutoSolve(false, true)
+	if b.String() == before {
+		t.Fatalf("the solver made no marks")
+	}
+	s.Rollback(inner)
+	if b.String() != before || s.Initialized() {
+		t.Fatalf("inner rollback: got %v", b)
+	}
+	s.InitSolve()
+	s.Rollback(outer)
+	if b.String() != before || s.Initialized() {
+		t.Fatalf("outer rollback: got %v", b)
+	}
+}
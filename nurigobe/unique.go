@@ -0,0 +1,119 @@
+package nurigobe
+
+import (
+	"context"
+	"fmt"
+)
+
+// SolutionCount is the outcome of a solution-counting search.
+type SolutionCount struct {
+	// Count is the number of distinct solutions found, at most the limit.
+	Count int
+	// Exhausted is true if the whole search space was explored, in which
+	// case Count is exact; otherwise there are at least Count solutions, and
+	// more than the limit unless the search was cancelled.
+	Exhausted bool
+	// Solutions holds one solved board per solution found.
+	Solutions []*Board
+}
+
+func (sc *SolutionCount) IsUnique() bool {
+	return sc.Exhausted && sc.Count == 1
+}
+
+func (sc *SolutionCount) String() string {
+	if !sc.Exhausted {
+		return fmt.Sprintf("at least %d solutions", sc.Count)
+	}
+	switch sc.Count {
+	case 0:
+		return "no solutions"
+	case 1:
+		return "1 solution (unique)"
+	}
+	return fmt.Sprintf("%d solutions", sc.Count)
+}
+
+// CountSolutions searches for up to limit solutions of def.
+func CountSolutions(def ProblemDef, limit int) *SolutionCount {
+	sc, _ := CountSolutionsContext(context.Background(), BoardFromDef(def), limit)
+	return sc
+}
+
+// CountSolutionsContext searches for up to limit solutions of b, which may be
+// partially marked. The deductive rules narrow the board down first; if they
+// get stuck, the search branches on an unknown cell and tries both colours.
+// The search only stops early once it finds a solution beyond the limit, so
+// a limit of 1 is enough to tell whether the solution is unique. b is not
+// modified. If ctx is cancelled, the solutions found so far are
+// returned along with ctx's error.
+func CountSolutionsContext(ctx context.Context, b *Board, limit int) (*SolutionCount, error) {
+	if limit < 1 {
+		limit = 1
+	}
+	sc := &SolutionCount{0, true, make([]*Board, 0, limit)}
+	s := NewSolverWithContext(ctx, b.Clone())
+	s.Progress = nil
+	s.InitSolve()
+	s.countSolutionsRec(sc, limit)
+	b.Watch.Add(s.b.Watch)
+	if err := ctx.Err(); err != nil {
+		sc.Exhausted = false
+		return sc, err
+	}
+	return sc, nil
+}
+
+func (s *Solver) countSolutionsRec(sc *SolutionCount, limit int) {
+	if s.Cancelled() {
+		return
+	}
+	s.AutoSolve(true, false)
+	if s.Cancelled() || s.b.ContainsError() != nil {
+		return
+	}
+	if s.b.TotalMarked == s.b.Problem.Size {
+		if solved, _ := s.b.IsSolved(); !solved {
+			return
+		}
+		if sc.Count == limit {
+			//one more than the limit, so the rest can be skipped
+			sc.Exhausted = false
+			return
+		}
+		sc.Count++
+		sc.Solutions = append(sc.Solutions, s.b.Clone())
+		return
+	}
+	target := s.b.BranchCell()
+	for _, cell := range []Cell{CLEAR, PAINTED} {
+		if !sc.Exhausted {
+			return
+		}
+		cp := s.b.Checkpoint()
+		branch := Solver{s.b, nil, s.ctx, true, s.rule, false, nil, s.steps, s.Action, nil, nil, nil, s.GuessWorkers, s.DeterministicGuesses, s.MaxSteps}
+		branch.b.Mark(target.Row, target.Col, cell)
+		branch.countSolutionsRec(sc, limit)
+		s.b.Rollback(cp)
+	}
+}
+
+// BranchCell picks the unknown cell to branch on when the rules are stuck,
+// preferring cells next to an island since those settle the most.
+func (b *Board) BranchCell() Coordinate {
+	first := NilCoordinate()
+	for r := 0; r < b.Problem.Height; r++ {
+		for c := 0; c < b.Problem.Width; c++ {
+			if b.Grid[r][c] != UNKNOWN {
+				continue
+			}
+			if first.IsNil() {
+				first = Coordinate{r, c}
+			}
+			if b.HasNeighborWith(SingleCoordinateSet(Coordinate{r, c}), CLEAR) {
+				return Coordinate{r, c}
+			}
+		}
+	}
+	return first
+}
@@ -0,0 +1,44 @@
+package nurigobe
+
+import "testing"
+
+func countSolutions(t *testing.T, input string, limit int) *SolutionCount {
+	def, err := DefFromString(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return CountSolutions(def, limit)
+}
+
+func TestCountSolutionsExactAtLimit(t *testing.T) {
+	for _, tc := range []struct {
+		input     string
+		limit     int
+		count     int
+		exhausted bool
+	}{
+		{"3__\n___\n___", 1, 1, false},
+		{"3__\n___\n___", 2, 2, true},
+		{"3__\n___\n___", 5, 2, true},
+		{"2__\n___\n__2", 2, 2, true},
+		{"_2_\n___\n_2_", 2, 2, false},
+		{"_2_\n___\n_2_", 4, 4, true},
+		{"____\n_2__\n___3", 3, 3, true},
+	} {
+		sc := countSolutions(t, tc.input, tc.limit)
+		if sc.Count != tc.count || sc.Exhausted != tc.exhausted || len(sc.Solutions) != sc.Count {
+			t.Errorf("%q with limit %d: got %d solutions (%d boards), exhausted %v; want %d, exhausted %v", tc.input, tc.limit, sc.Count, len(sc.Solutions), sc.Exhausted, tc.count, tc.exhausted)
+		}
+	}
+}
+
+func TestCountSolutionsUniqueWithLimitOne(t *testing.T) {
+	//the rules get stuck on this one, so the search has to branch
+	if sc := countSolutions(t, "___2\n____\n__4_\n____", 1); !sc.IsUnique() {
+		t.Fatalf("got %v, want a unique solution", sc)
+	}
+	b := readProblem(t, "problem4.txt")
+	if sc := CountSolutions(b.Problem, 1); !sc.IsUnique() {
+		t.Fatalf("problem4.txt: got %v, want a unique solution", sc)
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bismuthsalamander/nurikabe/nurigobe"
+	"github.com/chzyer/readline"
+)
+
+const playHelp = "arrows/hjkl move  x paint  . clear  space cycle  backspace erase  u undo  r redo  ? hint  c check  q quit"
+
+func play(args []string) {
+	flags := flag.NewFlagSet("play", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Printf("usage: %s play [problem.txt|url]\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return
+	}
+
+	fn := flags.Arg(0)
+	b, err := nurigobe.GetBoard(fn)
+	if err != nil {
+		fmt.Printf("error reading problem %s: %v\n", fn, err)
+		os.Exit(1)
+	}
+	fd := int(os.Stdin.Fd())
+	if !readline.IsTerminal(fd) {
+		fmt.Printf("play needs an interactive terminal\n")
+		os.Exit(1)
+	}
+	fmt.Printf("Loading...\n")
+	g := nurigobe.NewGame(context.Background(), b)
+	state, err := readline.MakeRaw(fd)
+	if err != nil {
+		fmt.Printf("error setting up terminal: %v\n", err)
+		os.Exit(1)
+	}
+	defer readline.Restore(fd, state)
+
+	cursor := nurigobe.Coordinate{Row: 0, Col: 0}
+	status := ""
+	in := bufio.NewReader(os.Stdin)
+	for {
+		drawGame(g, cursor, status)
+		status = ""
+		key, err := readKey(in)
+		if err != nil {
+			return
+		}
+		switch key {
+		case "up", "k":
+			if cursor.Row > 0 {
+				cursor.Row--
+			}
+		case "down", "j":
+			if cursor.Row < g.Problem.Height-1 {
+				cursor.Row++
+			}
+		case "left", "h":
+			if cursor.Col > 0 {
+				cursor.Col--
+			}
+		case "right", "l":
+			if cursor.Col < g.Problem.Width-1 {
+				cursor.Col++
+			}
+		case "x":
+			g.Set(cursor, nurigobe.PAINTED)
+		case ".":
+			g.Set(cursor, nurigobe.CLEAR)
+		case " ":
+			g.Cycle(cursor)
+		case "backspace":
+			g.Set(cursor, nurigobe.UNKNOWN)
+		case "u":
+			if !g.Undo() {
+				status = "Nothing to undo"
+			}
+		case "r":
+			if !g.Redo() {
+				status = "Nothing to redo"
+			}
+		case "?":
+			d, err := g.Hint(context.Background())
+			if err != nil {
+				status = fmt.Sprintf("No hint: %v", err)
+				break
+			}
+			cursor = d.Coord
+			status = "Hint: " + d.Explanation()
+		case "c":
+			if err := g.Check(); err != nil {
+				status = fmt.Sprintf("Mistake: %v", err)
+			} else if solved, _ := g.IsSolved(); solved {
+				status = "Solved!"
+			} else {
+				status = "No mistakes so far"
+			}
+		case "q", "ctrl-c":
+			drawGame(g, cursor, "")
+			return
+		}
+		if key != "c" && key != "?" && status == "" {
+			if solved, _ := g.IsSolved(); solved {
+				status = "Solved!"
+			}
+		}
+	}
+}
+
+// readKey reads one keypress, translating arrow-key escape sequences.
+func readKey(in *bufio.Reader) (string, error) {
+	ch, err := in.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	switch ch {
+	case 3:
+		return "ctrl-c", nil
+	case 8, 127:
+		return "backspace", nil
+	case 27:
+		if in.Buffered() < 2 {
+			return "escape", nil
+		}
+		if next, _ := in.ReadByte(); next != '[' {
+			return "escape", nil
+		}
+		switch dir, _ := in.ReadByte(); dir {
+		case 'A':
+			return "up", nil
+		case 'B':
+			return "down", nil
+		case 'C':
+			return "right", nil
+		case 'D':
+			return "left", nil
+		}
+		return "escape", nil
+	}
+	return string(ch), nil
+}
+
+// drawGame redraws the whole screen: the grid with the cursor in reverse
+// video, then the status and help lines. The terminal is in raw mode, so
+// lines end in \r\n.
+func drawGame(g *nurigobe.Game, cursor nurigobe.Coordinate, status string) {
+	clues := make(map[nurigobe.Coordinate]int)
+	for _, spec := range g.Problem.IslandSpecs {
+		clues[nurigobe.Coordinate{Row: spec.Row, Col: spec.Col}] = spec.Size
+	}
+	var sb strings.Builder
+	sb.WriteString("\033[H\033[2J")
+	for r := 0; r < g.Problem.Height; r++ {
+		for c := 0; c < g.Problem.Width; c++ {
+			coord := nurigobe.Coordinate{Row: r, Col: c}
+			text := " _"
+			if size, ok := clues[coord]; ok {
+				text = fmt.Sprintf("%2d", size)
+			} else if g.Get(coord) == nurigobe.PAINTED {
+				text = "##"
+			} else if g.Get(coord) == nurigobe.CLEAR {
+				text = " ."
+			}
+			if coord == cursor {
+				text = "\033[7m" + text + "\033[0m"
+			}
+			sb.WriteString(text)
+		}
+		sb.WriteString("\r\n")
+	}
+	sb.WriteString("\r\n" + status + "\r\n" + playHelp + "\r\n")
+	fmt.Print(sb.String())
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bismuthsalamander/nurikabe/nurigobe"
+)
+
+func render(args []string) {
+	flags := flag.NewFlagSet("render", flag.ExitOnError)
+	svg := flags.Bool("svg", false, "render as SVG")
+	pngOut := flags.Bool("png", false, "render as PNG")
+	gifOut := flags.Bool("gif", false, "render the solving process as an animated GIF")
+	every := flags.Int("every", 1, "number of marked cells per GIF frame")
+	out := flags.String("o", "", "write to `file` instead of standard output")
+	solve := flags.Bool("solve", false, "solve the puzzle before rendering it")
+	cellSize := flags.Int("cell", 32, "cell size in pixels")
+	highlight := flags.String("highlight", "", "comma-separated `cells` to highlight, e.g. r0c1,r0c2")
+	flags.Usage = func() {
+		fmt.Printf("usage: %s render --svg|--png|--gif [-o file] [-solve] [-cell N] [-highlight cells] [-every N] [problem.txt|url]\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	formats := 0
+	for _, f := range []bool{*svg, *pngOut, *gifOut} {
+		if f {
+			formats++
+		}
+	}
+	if flags.NArg() != 1 || formats != 1 {
+		flags.Usage()
+		return
+	}
+
+	fn := flags.Arg(0)
+	b, err := nurigobe.GetBoard(fn)
+	if err != nil {
+		fmt.Printf("error reading problem %s: %v\n", fn, err)
+		os.Exit(1)
+	}
+	var rec *nurigobe.Recording
+	if *solve || *gifOut {
+		opts := nurigobe.DefaultOptions()
+		opts.Record = *gifOut
+		res, err := nurigobe.SolveBoard(context.Background(), b, opts)
+		if err != nil {
+			fmt.Printf("error solving %s: %v\n", fn, err)
+		}
+		rec = res.Recording
+	}
+	var cells *nurigobe.CoordinateSet
+	if *highlight != "" {
+		cells, err = parseCells(b, *highlight)
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	w := os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Printf("error creating %s: %v\n", *out, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		w = f
+	}
+	switch {
+	case *svg:
+		opts := nurigobe.DefaultSVGOptions()
+		opts.CellSize = *cellSize
+		opts.Highlight = cells
+		err = b.WriteSVG(w, opts)
+	case *gifOut:
+		opts := nurigobe.DefaultGIFOptions()
+		opts.CellSize = *cellSize
+		opts.MarksPerFrame = *every
+		err = rec.WriteGIF(w, opts)
+	default:
+		opts := nurigobe.DefaultImageOptions()
+		opts.CellSize = *cellSize
+		opts.Highlight = cells
+		err = b.WritePNG(w, opts)
+	}
+	if err != nil {
+		fmt.Printf("error writing image: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// parseCells reads a comma-separated list of cells of b written as rNcM.
+func parseCells(b *nurigobe.Board, s string) (*nurigobe.CoordinateSet, error) {
+	cs := nurigobe.EmptyCoordinateSet()
+	for _, name := range strings.Split(s, ",") {
+		var c nurigobe.Coordinate
+		if _, err := fmt.Sscanf(strings.TrimSpace(name), "r%dc%d", &c.Row, &c.Col); err != nil {
+			return nil, fmt.Errorf("invalid cell %q (expected e.g. r0c1)", name)
+		}
+		if !b.IsInBounds(c) {
+			return nil, fmt.Errorf("cell %q is outside the %dx%d board", name, b.Problem.Width, b.Problem.Height)
+		}
+		cs.Add(c)
+	}
+	return cs, nil
+}
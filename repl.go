@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bismuthsalamander/nurikabe/nurigobe"
+	"github.com/chzyer/readline"
+)
+
+const replHelp = `commands:
+  load FILE|URL         load a puzzle
+  board                 print the board
+  step                  make the solver's next deduction
+  rule NAME             run one rule once (rule with no name lists them)
+  solve                 run the solver until it is stuck
+  islands [N]           list islands, or show island N's possibilities and reachable cells
+  walls                 list wall islands
+  diagonals             list diagonal sets
+  mark rNcM x|.         paint (x) or clear (.) a cell
+  guess rNcM x|.        try a cell and print the contradiction, if any
+  undo                  undo the last change
+  quit
+`
+
+// replSession holds the solver being inspected and a checkpoint from before
+// each change, for undo.
+type replSession struct {
+	s       *nurigobe.Solver
+	history []nurigobe.Checkpoint
+}
+
+func (rs *replSession) rules() map[string]func() bool {
+	s := rs.s
+	return map[string]func() bool{
+		"PaintTwoBorderedCells":       s.PaintTwoBorderedCells,
+		"ExtendIslandsOneLiberty":     s.ExtendIslandsOneLiberty,
+		"AddIslandBorders":            s.AddIslandBorders,
+		"PaintUnreachables":           s.PaintUnreachables,
+		"StripAllPossibilities":       s.StripAllPossibilities,
+		"ExtendWallIslandsOneLiberty": s.ExtendWallIslandsOneLiberty,
+		"ConnectUnrootedIslands":      s.ConnectUnrootedIslands,
+		"FindSinglePoolPreventers":    s.FindSinglePoolPreventers,
+		"FillIslandNecessaries":       s.FillIslandNecessaries,
+		"ExtendWallIslands":           s.ExtendWallIslands,
+		"FillElbows":                  s.FillElbows,
+		"EliminateIntolerables":       s.EliminateIntolerables,
+		"EliminateWallSplitters":      s.EliminateWallSplitters,
+	}
+}
+
+func repl(args []string) {
+	rl, err := readline.New("nurikabe> ")
+	if err != nil {
+		fmt.Printf("error starting REPL: %v\n", err)
+		os.Exit(1)
+	}
+	defer rl.Close()
+	rs := &replSession{}
+	if len(args) > 0 {
+		rs.run(rl.Stdout(), []string{"load", args[0]})
+	}
+	for {
+		line, err := rl.Readline()
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "quit" || fields[0] == "exit" {
+			return
+		}
+		rs.run(rl.Stdout(), fields)
+	}
+}
+
+func (rs *replSession) run(w io.Writer, fields []string) {
+	cmd, args := fields[0], fields[1:]
+	if cmd == "help" {
+		fmt.Fprint(w, replHelp)
+		return
+	}
+	if cmd == "load" {
+		if len(args) != 1 {
+			fmt.Fprintf(w, "usage: load FILE|URL\n")
+			return
+		}
+		b, err := nurigobe.GetBoard(args[0])
+		if err != nil {
+			fmt.Fprintf(w, "error reading problem %s: %v\n", args[0], err)
+			return
+		}
+		rs.s = nurigobe.NewSolver(b)
+		rs.s.Progress = nil
+		rs.history = nil
+		fmt.Fprintf(w, "%v\n", b)
+		return
+	}
+	if rs.s == nil {
+		fmt.Fprintf(w, "no puzzle loaded; use load FILE|URL or help\n")
+		return
+	}
+	b := rs.s.Board()
+	switch cmd {
+	case "board":
+		fmt.Fprintf(w, "%v\n", b)
+	case "step":
+		rs.save()
+		d := rs.s.NextDeduction()
+		if d == nil {
+			rs.undo()
+			fmt.Fprintf(w, "no deduction found\n")
+			return
+		}
+		fmt.Fprintf(w, "%v [%s]\n", d, d.Rule)
+	case "rule":
+		rules := rs.rules()
+		if len(args) != 1 || rules[args[0]] == nil {
+			names := make([]string, 0, len(rules))
+			for name := range rules {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			fmt.Fprintf(w, "rules: %s\n", strings.Join(names, " "))
+			return
+		}
+		rs.save()
+		if !rs.s.Initialized() {
+			rs.s.InitSolve()
+		}
+		before := b.TotalMarked
+		changed := rules[args[0]]()
+		marked := b.TotalMarked - before
+		if !changed {
+			rs.undo()
+		}
+		fmt.Fprintf(w, "%s: changed=%v, marked %d cell(s)\n", args[0], changed, marked)
+	case "solve":
+		rs.save()
+		if !rs.s.Initialized() {
+			rs.s.InitSolve()
+		}
+		rs.s.AutoSolve(true, false)
+		fmt.Fprintf(w, "%v\n", b)
+	case "islands":
+		if len(args) == 0 {
+			for idx, i := range b.Islands {
+				fmt.Fprintf(w, "%d: %v size %d/%d, %d possibilities: %s\n", idx, i.Ref(), i.CurrentSize, i.TargetSize, len(i.Possibilities), i.Members.SortedString())
+			}
+			return
+		}
+		idx, err := strconv.Atoi(args[0])
+		if err != nil || idx < 0 || idx >= len(b.Islands) {
+			fmt.Fprintf(w, "no island %q\n", args[0])
+			return
+		}
+		i := b.Islands[idx]
+		fmt.Fprintf(w, "%v members: %s\n", i.Ref(), i.Members.SortedString())
+		if i.Reachable != nil {
+			fmt.Fprintf(w, "reachable: %s\n", i.Reachable.SortedString())
+		}
+		for pi, p := range i.Possibilities {
+			fmt.Fprintf(w, "  %d: %s\n", pi, p.Minus(i.Members).SortedString())
+		}
+	case "walls":
+		for idx, i := range b.WallIslands {
+			fmt.Fprintf(w, "%d: size %d: %s\n", idx, i.CurrentSize, i.Members.SortedString())
+		}
+	case "diagonals":
+		for idx, cs := range b.DiagonalSets {
+			fmt.Fprintf(w, "%d: %s\n", idx, cs.SortedString())
+		}
+	case "mark", "guess":
+		if len(args) != 2 || (args[1] != "x" && args[1] != ".") {
+			fmt.Fprintf(w, "usage: %s rNcM x|.\n", cmd)
+			return
+		}
+		cells, err := parseCells(b, args[0])
+		if err != nil {
+			fmt.Fprintf(w, "%v\n", err)
+			return
+		}
+		if cells.Size() != 1 {
+			fmt.Fprintf(w, "invalid cell %q\n", args[0])
+			return
+		}
+		c := cells.First()
+		cell := nurigobe.Cell(nurigobe.PAINTED)
+		if args[1] == "." {
+			cell = nurigobe.CLEAR
+		}
+		if b.Get(c) != nurigobe.UNKNOWN {
+			fmt.Fprintf(w, "r%dc%d is already marked\n", c.Row, c.Col)
+			return
+		}
+		if cmd == "guess" {
+			//guesses need possibilities, so initialize if necessary and
+			//roll that back afterwards
+			cp := rs.s.Checkpoint()
+			if !rs.s.Initialized() {
+				rs.s.InitSolve()
+			}
+			err := rs.s.FalsifyGuess(c.Row, c.Col, cell, false)
+			rs.s.Rollback(cp)
+			if err != nil {
+				fmt.Fprintf(w, "contradiction: %v\n", err)
+			} else {
+				fmt.Fprintf(w, "no contradiction found\n")
+			}
+			return
+		}
+		rs.save()
+		rs.s.Mark(c.Row, c.Col, cell)
+		fmt.Fprintf(w, "%v\n", b)
+	case "undo":
+		if len(rs.history) == 0 {
+			fmt.Fprintf(w, "nothing to undo\n")
+			return
+		}
+		rs.undo()
+		fmt.Fprintf(w, "%v\n", b)
+	default:
+		fmt.Fprintf(w, "unknown command %q; try help\n", cmd)
+	}
+}
+
+func (rs *replSession) save() {
+	rs.history = append(rs.history, rs.s.Checkpoint())
+}
+
+// undo rolls the solver back to the last checkpoint in the history.
+func (rs *replSession) undo() {
+	rs.s.Rollback(rs.history[len(rs.history)-1])
+	rs.history = rs.history[:len(rs.history)-1]
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bismuthsalamander/nurikabe/nurigobe"
+)
+
+// maxPuzzleBytes bounds the size of a posted puzzle.
+const maxPuzzleBytes = 1 << 20
+
+func serve(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", ":8080", "address to listen on")
+	timeout := flags.Duration("timeout", 10*time.Second, "longest time to spend on one request")
+	flags.Usage = func() {
+		fmt.Printf("usage: %s serve [-addr host:port] [-timeout duration]\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return
+	}
+
+	log.Printf("listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, newServer(*timeout)))
+}
+
+// server answers puzzle requests over HTTP. Every request parses its own
+// Board and runs its own Solver, so requests can be handled concurrently.
+type server struct {
+	timeout time.Duration
+}
+
+func newServer(timeout time.Duration) http.Handler {
+	s := &server{timeout}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/solve", s.handle(s.solve))
+	mux.HandleFunc("/hint", s.handle(s.hint))
+	mux.HandleFunc("/validate", s.handle(s.validate))
+	mux.HandleFunc("/unique", s.handle(s.unique))
+	mux.HandleFunc("/stream", s.stream)
+	return mux
+}
+
+// httpError is an error with the HTTP status it should be reported with.
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e *httpError) Error() string {
+	return e.err.Error()
+}
+
+// handle adapts a handler that takes a parsed board and returns a value to
+// encode as JSON. The request body holds the puzzle in any format ParseBoard
+// accepts, or a puzz.link URL; the optional timeout query parameter can
+// shorten the server's timeout.
+func (s *server) handle(h func(ctx context.Context, r *http.Request, b *nurigobe.Board) (interface{}, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, errorResponse{"use POST with the puzzle as the body"})
+			return
+		}
+		timeout := s.timeout
+		if t := r.URL.Query().Get("timeout"); t != "" {
+			d, err := time.ParseDuration(t)
+			if err != nil || d <= 0 {
+				writeJSON(w, http.StatusBadRequest, errorResponse{fmt.Sprintf("invalid timeout %q", t)})
+				return
+			}
+			if d < timeout {
+				timeout = d
+			}
+		}
+		b, err := readPuzzle(w, r)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{err.Error()})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		out, err := h(ctx, r, b)
+		var he *httpError
+		switch {
+		case errors.As(err, &he):
+			writeJSON(w, he.status, errorResponse{he.Error()})
+		case errors.Is(err, context.Canceled):
+			//the client went away, so there is no one to answer
+			return
+		case errors.Is(err, context.DeadlineExceeded):
+			writeJSON(w, http.StatusGatewayTimeout, timeoutResponse{err.Error(), out})
+		case err != nil:
+			writeJSON(w, http.StatusInternalServerError, errorResponse{err.Error()})
+		default:
+			writeJSON(w, http.StatusOK, out)
+		}
+	}
+}
+
+// readPuzzle parses the puzzle in the request body, or in the puzzle query
+// parameter of a GET request.
+func readPuzzle(w http.ResponseWriter, r *http.Request) (*nurigobe.Board, error) {
+	var input string
+	if r.Method == http.MethodGet {
+		input = r.URL.Query().Get("puzzle")
+		if input == "" {
+			return nil, fmt.Errorf("no puzzle given")
+		}
+	} else {
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPuzzleBytes))
+		if err != nil {
+			return nil, fmt.Errorf("error reading puzzle: %v", err)
+		}
+		input = string(data)
+	}
+	input = strings.TrimSpace(input)
+	if nurigobe.IsPuzzLinkURL(input) {
+		def, err := nurigobe.DefFromPuzzLink(input)
+		if err != nil {
+			return nil, err
+		}
+		return nurigobe.BoardFromDef(def), nil
+	}
+	return nurigobe.ParseBoard(input)
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type timeoutResponse struct {
+	Error   string      `json:"error"`
+	Partial interface{} `json:"partial,omitempty"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// solveOptions returns the options requests solve with. Requests are
+// already served concurrently, so each one tests its guesses one at a time
+// rather than starting a pool of its own.
+func solveOptions() nurigobe.Options {
+	opts := nurigobe.DefaultOptions()
+	opts.GuessWorkers = 1
+	return opts
+}
+
+// solve solves the puzzle; ?trace=1 includes every deduction.
+func (s *server) solve(ctx context.Context, r *http.Request, b *nurigobe.Board) (interface{}, error) {
+	opts := solveOptions()
+	opts.Trace = r.URL.Query().Get("trace") != ""
+	return nurigobe.SolveBoard(ctx, b, opts)
+}
+
+// newGame wraps b in a game. Solving the puzzle can take up the whole
+// timeout, so it is only solved if ?mistakes=1 asks for the grid to be
+// checked against the solution.
+func newGame(ctx context.Context, r *http.Request, b *nurigobe.Board) *nurigobe.Game {
+	if r.URL.Query().Get("mistakes") != "" {
+		return nurigobe.NewGame(ctx, b)
+	}
+	return nurigobe.NewGameWithoutSolution(b)
+}
+
+// hint returns the next deduction from the posted, partially solved grid,
+// in the same form as a step of a trace.
+func (s *server) hint(ctx context.Context, r *http.Request, b *nurigobe.Board) (interface{}, error) {
+	g := newGame(ctx, r, b)
+	d, err := g.Hint(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, &httpError{http.StatusUnprocessableEntity, err}
+	}
+	return d, nil
+}
+
+type validateResponse struct {
+	Solved bool `json:"solved"`
+	// Violation is the first rule the grid breaks, if any.
+	Violation string `json:"violation,omitempty"`
+	// Mistakes are the cells that disagree with the solution, if it was
+	// asked for and the solver found it in time.
+	Mistakes      []string `json:"mistakes"`
+	SolutionKnown bool     `json:"solutionKnown"`
+}
+
+// validate checks the posted grid against the rules and, with ?mistakes=1,
+// the solution.
+func (s *server) validate(ctx context.Context, r *http.Request, b *nurigobe.Board) (interface{}, error) {
+	g := newGame(ctx, r, b)
+	resp := validateResponse{Mistakes: make([]string, 0)}
+	resp.Solved, _ = g.IsSolved()
+	if err := g.Violation(); err != nil {
+		resp.Violation = err.Error()
+	}
+	mistakes := g.Mistakes()
+	resp.SolutionKnown = mistakes != nil
+	for _, c := range mistakes {
+		resp.Mistakes = append(resp.Mistakes, fmt.Sprintf("r%dc%d", c.Row, c.Col))
+	}
+	return resp, nil
+}
+
+type uniqueResponse struct {
+	Count     int    `json:"count"`
+	Exhausted bool   `json:"exhausted"`
+	Unique    bool   `json:"unique"`
+	Summary   string `json:"summary"`
+}
+
+// unique counts solutions up to ?limit=N (default 2).
+func (s *server) unique(ctx context.Context, r *http.Request, b *nurigobe.Board) (interface{}, error) {
+	limit := 2
+	if l := r.URL.Query().Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 {
+			return nil, &httpError{http.StatusBadRequest, fmt.Errorf("invalid limit %q", l)}
+		}
+		limit = n
+	}
+	sc, err := nurigobe.CountSolutionsContext(ctx, b, limit)
+	return uniqueResponse{sc.Count, sc.Exhausted, sc.IsUnique(), sc.String()}, err
+}
+
+type progressEvent struct {
+	Action      string `json:"action"`
+	TotalMarked int    `json:"totalMarked"`
+	GridSize    int    `json:"gridSize"`
+	Row         *int   `json:"row,omitempty"`
+	Col         *int   `json:"col,omitempty"`
+	Cell        string `json:"cell,omitempty"`
+}
+
+// stream solves the puzzle and reports its progress as Server-Sent Events,
+// so it works with a browser's EventSource (pass the puzzle as ?puzzle=...)
+// as well as with a POSTed body. The events are:
+//
+//	start     the starting board, as in a solve result
+//	progress  the current action, the number of marked cells and, when a
+//	          cell was just marked, its row, column and colour
+//	done      the solve result
+//
+// Updates that repeat the previous action without marking a cell are
+// dropped.
+func (s *server) stream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{"use GET with ?puzzle= or POST with the puzzle as the body"})
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, errorResponse{"streaming is not supported"})
+		return
+	}
+	b, err := readPuzzle(w, r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{err.Error()})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), s.timeout)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	send := func(event string, v interface{}) {
+		data, err := json.Marshal(v)
+		if err != nil {
+			data, _ = json.Marshal(errorResponse{err.Error()})
+		}
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+		flusher.Flush()
+	}
+	send("start", b)
+
+	opts := solveOptions()
+	opts.Progress = make(chan nurigobe.ProgressUpdate, b.Problem.Size*2)
+	done := make(chan *nurigobe.Result, 1)
+	go func() {
+		res, _ := nurigobe.SolveBoard(ctx, b, opts)
+		done <- res
+	}()
+	lastAction := ""
+	for update := range opts.Progress {
+		marked := !update.Marked.IsNil()
+		if !marked && update.CurrentAction == lastAction {
+			continue
+		}
+		lastAction = update.CurrentAction
+		ev := progressEvent{Action: update.CurrentAction, TotalMarked: update.TotalMarked, GridSize: update.GridSize}
+		if marked {
+			row, col := update.Marked.Row, update.Marked.Col
+			ev.Row, ev.Col = &row, &col
+			ev.Cell = "painted"
+			if update.Cell == nurigobe.CLEAR {
+				ev.Cell = "clear"
+			}
+		}
+		send("progress", ev)
+	}
+	res := <-done
+	if err := ctx.Err(); err != nil {
+		send("error", errorResponse{err.Error()})
+	}
+	send("done", res)
+}